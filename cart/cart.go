@@ -0,0 +1,159 @@
+// cart/cart.go
+package cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MaxItems 一个购物车最多放几件
+const MaxItems = 20
+
+var (
+	ErrHeld     = errors.New("item is held in another user's cart")
+	ErrCartFull = errors.New("cart is full")
+)
+
+// Store 每个用户一个购物车（集合）；加入的物品各有一个临时占用（hold），到期自动释放
+//
+//	cart:hold:<itemID>  = userID，TTL 即占用时长
+//	cart:user:<userID>  = {itemID...}，成员的占用可能已过期，读取时清理
+type Store struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+func NewStore(rdb *redis.Client, ttl time.Duration) *Store {
+	return &Store{rdb: rdb, ttl: ttl}
+}
+
+// Hold 某件物品被谁占用到什么时候
+type Hold struct {
+	ItemID    string    `json:"itemId"`
+	UserID    string    `json:"userId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func holdKey(itemID string) string { return fmt.Sprintf("cart:hold:%s", itemID) }
+func cartKey(userID string) string { return fmt.Sprintf("cart:user:%s", userID) }
+
+// 空闲或本人已占用 → (重新)占用并放入购物车；返回 1 成功，0 他人占用，-1 购物车已满
+var addScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if cur and cur ~= ARGV[1] then return 0 end
+if redis.call('SISMEMBER', KEYS[2], ARGV[3]) == 0 and redis.call('SCARD', KEYS[2]) >= tonumber(ARGV[4]) then
+  return -1
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+redis.call('SADD', KEYS[2], ARGV[3])
+redis.call('PEXPIRE', KEYS[2], ARGV[2])
+return 1
+`)
+
+// 只释放本人的占用
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then redis.call('DEL', KEYS[1]) end
+redis.call('SREM', KEYS[2], ARGV[2])
+return 1
+`)
+
+// Add 放入购物车并占用 ttl；本人再次加入会续期
+func (s *Store) Add(ctx context.Context, userID, itemID string) (*Hold, error) {
+	n, err := addScript.Run(ctx, s.rdb,
+		[]string{holdKey(itemID), cartKey(userID)},
+		userID, s.ttl.Milliseconds(), itemID, MaxItems).Int()
+	if err != nil {
+		return nil, err
+	}
+	switch n {
+	case 0:
+		return nil, ErrHeld
+	case -1:
+		return nil, ErrCartFull
+	}
+	return &Hold{ItemID: itemID, UserID: userID, ExpiresAt: time.Now().Add(s.ttl)}, nil
+}
+
+// Remove 移出购物车并释放占用
+func (s *Store) Remove(ctx context.Context, userID string, itemIDs ...string) error {
+	for _, id := range itemIDs {
+		if err := releaseScript.Run(ctx, s.rdb, []string{holdKey(id), cartKey(userID)}, userID, id).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Items 购物车中仍由本人占用的物品；占用已过期（或已被他人占用）的顺手移出
+func (s *Store) Items(ctx context.Context, userID string) ([]Hold, error) {
+	ids, err := s.rdb.SMembers(ctx, cartKey(userID)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	holds, err := s.Holds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Hold, 0, len(ids))
+	var stale []any
+	for _, id := range ids {
+		if h, ok := holds[id]; ok && h.UserID == userID {
+			out = append(out, h)
+		} else {
+			stale = append(stale, id)
+		}
+	}
+	if len(stale) > 0 {
+		_ = s.rdb.SRem(ctx, cartKey(userID), stale...).Err()
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ExpiresAt.Before(out[j].ExpiresAt) })
+	return out, nil
+}
+
+// Holds 批量查询占用情况，未被占用的不在结果里
+func (s *Store) Holds(ctx context.Context, itemIDs []string) (map[string]Hold, error) {
+	out := map[string]Hold{}
+	if len(itemIDs) == 0 {
+		return out, nil
+	}
+	pipe := s.rdb.Pipeline()
+	users := make([]*redis.StringCmd, len(itemIDs))
+	ttls := make([]*redis.DurationCmd, len(itemIDs))
+	for i, id := range itemIDs {
+		users[i] = pipe.Get(ctx, holdKey(id))
+		ttls[i] = pipe.PTTL(ctx, holdKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i, id := range itemIDs {
+		uid, err := users[i].Result()
+		if err != nil {
+			continue
+		}
+		ttl := ttls[i].Val()
+		if ttl <= 0 {
+			continue
+		}
+		out[id] = Hold{ItemID: id, UserID: uid, ExpiresAt: now.Add(ttl)}
+	}
+	return out, nil
+}
+
+// HeldByOther 物品是否被 userID 以外的人占用
+func (s *Store) HeldByOther(ctx context.Context, userID, itemID string) (*Hold, error) {
+	holds, err := s.Holds(ctx, []string{itemID})
+	if err != nil {
+		return nil, err
+	}
+	if h, ok := holds[itemID]; ok && h.UserID != userID {
+		return &h, nil
+	}
+	return nil, nil
+}
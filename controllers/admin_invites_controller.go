@@ -7,11 +7,11 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"net/smtp"
-	"os"
 	"strings"
 	"time"
 
+	"Gin_postgres_redis_rent_tool/mailer"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -73,46 +73,15 @@ func (ic *InviteController) CreateInvite(c *gin.Context) {
 
 // -------------------- 邮件发送 --------------------
 
-type smtpConf struct {
-	Host     string // SMTP_HOST, e.g. smtp.gmail.com
-	Port     string // SMTP_PORT, e.g. 587
-	Username string // SMTP_USERNAME, e.g. your@gmail.com
-	Password string // SMTP_PASSWORD, app password or smtp password
-	From     string // SMTP_FROM,    e.g. no-reply@yourdomain.com (为空时回退 Username)
-	AppName  string // APP_NAME,     e.g. Rent Tool
-}
-
-func (ic *InviteController) loadSMTP() smtpConf {
-	get := func(k, d string) string {
-		if v := strings.TrimSpace(os.Getenv(k)); v != "" {
-			return v
-		}
-		return d
-	}
-	return smtpConf{
-		Host:     get("SMTP_HOST", ""),
-		Port:     get("SMTP_PORT", "587"),
-		Username: get("SMTP_USERNAME", ""),
-		Password: get("SMTP_PASSWORD", ""),
-		From:     get("SMTP_FROM", ""),
-		AppName:  get("APP_NAME", "Rent Tool"),
-	}
-}
-
 func (ic *InviteController) sendInviteMail(toEmail, link string, expiresDays int) error {
-	conf := ic.loadSMTP()
+	conf := mailer.Load()
 
 	// 未配置 SMTP → 开发模式：打印即可，不报错
-	if conf.Host == "" || (conf.Username == "" && conf.From == "") {
+	if !conf.Configured() {
 		log.Printf("[DEV] Invite link for %s: %s (expires in %d day(s))", toEmail, link, expiresDays)
 		return nil
 	}
 
-	fromAddr := conf.From
-	if fromAddr == "" {
-		fromAddr = conf.Username
-	}
-
 	subject := fmt.Sprintf("%s Invitation", conf.AppName)
 	htmlBody := fmt.Sprintf(`
 <div style="font-family:Arial,sans-serif; font-size:14px; color:#222">
@@ -131,20 +100,5 @@ func (ic *InviteController) sendInviteMail(toEmail, link string, expiresDays int
 </div>
 `, conf.AppName, link, link, link, expiresDays)
 
-	msg := buildMIMEWithFromName(conf.AppName, fromAddr, toEmail, subject, htmlBody)
-
-	auth := smtp.PlainAuth("", conf.Username, conf.Password, conf.Host)
-	addr := conf.Host + ":" + conf.Port
-	return smtp.SendMail(addr, auth, fromAddr, []string{toEmail}, []byte(msg))
-}
-
-func buildMIMEWithFromName(fromName, fromAddr, to, subject, html string) string {
-	headers := []string{
-		fmt.Sprintf("From: %s <%s>", fromName, fromAddr),
-		fmt.Sprintf("To: %s", to),
-		fmt.Sprintf("Subject: %s", subject),
-		"MIME-Version: 1.0",
-		"Content-Type: text/html; charset=UTF-8",
-	}
-	return strings.Join(headers, "\r\n") + "\r\n\r\n" + html
+	return conf.Send(toEmail, subject, htmlBody)
 }
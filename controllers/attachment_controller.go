@@ -0,0 +1,287 @@
+// controllers/attachment_controller.go
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/models"
+	"Gin_postgres_redis_rent_tool/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AttachmentController struct{ *Srv }
+
+func NewAttachmentController(s *Srv) *AttachmentController { return &AttachmentController{Srv: s} }
+
+const thumbMaxSide = 256
+
+var (
+	imageTypes = map[string]bool{"image/jpeg": true, "image/png": true, "image/gif": true, "image/webp": true}
+	docTypes   = map[string]bool{"application/pdf": true, "image/jpeg": true, "image/png": true, "image/gif": true, "image/webp": true}
+
+	// 物品附件：种类 → 允许的类型
+	itemAttachmentKinds = map[string]map[string]bool{
+		models.AttachKindManual:      docTypes,
+		models.AttachKindCertificate: docTypes,
+		models.AttachKindPhoto:       imageTypes,
+	}
+)
+
+// 单个文件上限：BLOB_MAX_MB，默认 20MB
+func maxUploadBytes() int64 {
+	if n, err := strconv.Atoi(os.Getenv("BLOB_MAX_MB")); err == nil && n > 0 {
+		return int64(n) << 20
+	}
+	return 20 << 20
+}
+
+// upload 读取表单文件 → 校验大小/类型 → 写 blob（图片额外生成缩略图）→ 落库
+// 出错时已写响应，返回 nil
+func (ac *AttachmentController) upload(c *gin.Context, ownerType, ownerID, kind string, allowed map[string]bool) *models.Attachment {
+	limit := maxUploadBytes()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+(1<<20)) // 预留 multipart 头部
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			c.JSON(http.StatusRequestEntityTooLarge, app.H{"error": fmt.Sprintf("file exceeds %d MB", limit>>20)})
+			return nil
+		}
+		c.JSON(http.StatusBadRequest, app.H{"error": "missing file"})
+		return nil
+	}
+	if fh.Size > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, app.H{"error": fmt.Sprintf("file exceeds %d MB", limit>>20)})
+		return nil
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return nil
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return nil
+	}
+	if int64(len(data)) > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, app.H{"error": fmt.Sprintf("file exceeds %d MB", limit>>20)})
+		return nil
+	}
+
+	// 以内容嗅探为准，不信任客户端的 Content-Type
+	ct, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if !allowed[ct] {
+		c.JSON(http.StatusUnsupportedMediaType, app.H{"error": "unsupported content type " + ct})
+		return nil
+	}
+	// 生成缩略图要整张解码，尺寸过大的图片直接拒收
+	if imageTypes[ct] {
+		if err := storage.CheckImageSize(data); err != nil {
+			c.JSON(http.StatusRequestEntityTooLarge, app.H{"error": err.Error()})
+			return nil
+		}
+	}
+
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	ctx := c.Request.Context()
+	id := uuid.NewString()
+	a := &models.Attachment{
+		ID:          id,
+		OwnerType:   ownerType,
+		OwnerID:     ownerID,
+		Kind:        kind,
+		FileName:    filepath.Base(fh.Filename),
+		ContentType: ct,
+		Size:        int64(len(data)),
+		BlobKey:     fmt.Sprintf("%s/%s/%s", ownerType, ownerID, id),
+		UploadedBy:  uid,
+	}
+	if err := ac.Blobs.Put(ctx, a.BlobKey, bytes.NewReader(data), a.Size, ct); err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return nil
+	}
+	if imageTypes[ct] {
+		if thumb, err := storage.MakeThumbnail(data, thumbMaxSide); err == nil {
+			key := a.BlobKey + ".thumb.jpg"
+			if err := ac.Blobs.Put(ctx, key, bytes.NewReader(thumb), int64(len(thumb)), "image/jpeg"); err == nil {
+				a.ThumbKey = &key
+				a.HasThumb = true
+			}
+		} else {
+			log.Printf("[attachment] thumbnail %s: %v", id, err)
+		}
+	}
+	if err := ac.Repo.CreateAttachment(ctx, a); err != nil {
+		ac.removeBlobs(ctx, a)
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return nil
+	}
+	return a
+}
+
+func (ac *AttachmentController) removeBlobs(ctx context.Context, a *models.Attachment) {
+	if err := ac.Blobs.Delete(ctx, a.BlobKey); err != nil {
+		log.Printf("[attachment] delete blob %s: %v", a.BlobKey, err)
+	}
+	if a.ThumbKey != nil {
+		_ = ac.Blobs.Delete(ctx, *a.ThumbKey)
+	}
+}
+
+// POST /api/admin/items/:id/attachments   multipart: file, kind=manual|photo|certificate
+func (ac *AttachmentController) UploadItemAttachment(c *gin.Context) {
+	itemID := c.Param("id")
+	kind := c.DefaultPostForm("kind", models.AttachKindPhoto)
+	allowed, ok := itemAttachmentKinds[kind]
+	if !ok {
+		c.JSON(http.StatusBadRequest, app.H{"error": "kind must be manual, photo or certificate"})
+		return
+	}
+	if _, err := ac.Repo.FindItemByID(c.Request.Context(), itemID); err != nil {
+		c.JSON(http.StatusNotFound, app.H{"error": "item not found"})
+		return
+	}
+	if a := ac.upload(c, models.AttachOwnerItem, itemID, kind, allowed); a != nil {
+		c.JSON(http.StatusCreated, a)
+	}
+}
+
+// POST /api/items/loans/:loanId/photos   multipart: file, stage=checkout|return（借用人或管理员）
+func (ac *AttachmentController) UploadLoanPhoto(c *gin.Context) {
+	loan, err := ac.Repo.FindLoanByID(c.Request.Context(), c.Param("loanId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, app.H{"error": "loan not found"})
+		return
+	}
+	v, _ := c.Get("userID")
+	if uid, _ := v.(string); uid != loan.UserID && !c.GetBool("isAdmin") {
+		c.JSON(http.StatusForbidden, app.H{"error": "forbidden"})
+		return
+	}
+	kind := models.AttachKindCheckout
+	switch c.DefaultPostForm("stage", "checkout") {
+	case "checkout":
+	case "return":
+		kind = models.AttachKindReturn
+	default:
+		c.JSON(http.StatusBadRequest, app.H{"error": "stage must be checkout or return"})
+		return
+	}
+	if a := ac.upload(c, models.AttachOwnerLoan, loan.ID, kind, imageTypes); a != nil {
+		c.JSON(http.StatusCreated, a)
+	}
+}
+
+// GET /api/items/loans/:loanId/photos
+func (ac *AttachmentController) ListLoanPhotos(c *gin.Context) {
+	loan, err := ac.Repo.FindLoanByID(c.Request.Context(), c.Param("loanId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, app.H{"error": "loan not found"})
+		return
+	}
+	v, _ := c.Get("userID")
+	if uid, _ := v.(string); uid != loan.UserID && !c.GetBool("isAdmin") {
+		c.JSON(http.StatusForbidden, app.H{"error": "forbidden"})
+		return
+	}
+	as, err := ac.Repo.ListAttachments(c.Request.Context(), models.AttachOwnerLoan, loan.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": as})
+}
+
+// 借用照片只有借用人和管理员能看；物品附件登录即可
+func (ac *AttachmentController) loadForRead(c *gin.Context) *models.Attachment {
+	a, err := ac.Repo.FindAttachment(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, app.H{"error": "attachment not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		}
+		return nil
+	}
+	if a.OwnerType == models.AttachOwnerLoan && !c.GetBool("isAdmin") {
+		v, _ := c.Get("userID")
+		uid, _ := v.(string)
+		loan, err := ac.Repo.FindLoanByID(c.Request.Context(), a.OwnerID)
+		if err != nil || loan.UserID != uid {
+			c.JSON(http.StatusForbidden, app.H{"error": "forbidden"})
+			return nil
+		}
+	}
+	return a
+}
+
+func (ac *AttachmentController) stream(c *gin.Context, key, contentType string, size int64, fileName string) {
+	rc, err := ac.Blobs.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, app.H{"error": "file missing"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	defer rc.Close()
+	headers := map[string]string{}
+	if fileName != "" {
+		headers["Content-Disposition"] = mime.FormatMediaType("attachment", map[string]string{"filename": fileName})
+	}
+	c.DataFromReader(http.StatusOK, size, contentType, rc, headers)
+}
+
+// GET /api/attachments/:id
+func (ac *AttachmentController) Download(c *gin.Context) {
+	if a := ac.loadForRead(c); a != nil {
+		ac.stream(c, a.BlobKey, a.ContentType, a.Size, a.FileName)
+	}
+}
+
+// GET /api/attachments/:id/thumbnail
+func (ac *AttachmentController) Thumbnail(c *gin.Context) {
+	a := ac.loadForRead(c)
+	if a == nil {
+		return
+	}
+	if a.ThumbKey == nil {
+		c.JSON(http.StatusNotFound, app.H{"error": "no thumbnail"})
+		return
+	}
+	ac.stream(c, *a.ThumbKey, "image/jpeg", -1, "")
+}
+
+// DELETE /api/admin/attachments/:id
+func (ac *AttachmentController) Delete(c *gin.Context) {
+	a, err := ac.Repo.DeleteAttachment(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, app.H{"error": "attachment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	ac.removeBlobs(c.Request.Context(), a)
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
@@ -0,0 +1,186 @@
+// controllers/billing_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+	"Gin_postgres_redis_rent_tool/invoice"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type BillingController struct{ *Srv }
+
+func NewBillingController(s *Srv) *BillingController { return &BillingController{Srv: s} }
+
+func writeBillingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, app.H{"error": err.Error()})
+	case errors.Is(err, db.ErrInvalidLedger), errors.Is(err, db.ErrInvalidPeriod):
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+	case errors.Is(err, db.ErrNotWaivable), errors.Is(err, db.ErrAlreadyWaived):
+		c.JSON(http.StatusConflict, app.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+	}
+}
+
+// ledger 账本明细 + 当前余额；from / to 为 RFC3339
+func (bc *BillingController) ledger(c *gin.Context, userID string) {
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": "from must be RFC3339"})
+		return
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": "to must be RFC3339"})
+		return
+	}
+	rows, err := bc.Repo.ListLedger(c.Request.Context(), db.LedgerQuery{
+		UserID: userID,
+		Kind:   c.Query("kind"),
+		From:   from,
+		To:     to,
+	})
+	if err != nil {
+		writeBillingError(c, err)
+		return
+	}
+	out := app.H{"items": rows}
+	if userID != "" {
+		bal, err := bc.Repo.UserBalance(c.Request.Context(), userID, nil)
+		if err != nil {
+			writeBillingError(c, err)
+			return
+		}
+		out["balanceCents"] = bal
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// invoice 某账期的账单；format=pdf 时输出 PDF，否则 JSON
+func (bc *BillingController) invoice(c *gin.Context, userID string) {
+	inv, err := bc.Repo.BuildInvoice(c.Request.Context(), userID, c.Param("period"))
+	if err != nil {
+		writeBillingError(c, err)
+		return
+	}
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, inv)
+	case "pdf":
+		b, err := invoice.PDF(invoiceDoc(inv))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+inv.Number+`.pdf"`)
+		c.Data(http.StatusOK, "application/pdf", b)
+	default:
+		c.JSON(http.StatusBadRequest, app.H{"error": "format must be json or pdf"})
+	}
+}
+
+func invoiceDoc(inv *db.Invoice) invoice.Doc {
+	d := invoice.Doc{
+		Number:        inv.Number,
+		Period:        inv.Period,
+		Customer:      inv.DisplayName + " (" + inv.Username + ")",
+		PeriodStart:   inv.PeriodStart,
+		PeriodEnd:     inv.PeriodEnd,
+		Currency:      inv.Currency,
+		GeneratedAt:   inv.GeneratedAt,
+		OpeningCents:  inv.OpeningBalanceCents,
+		ChargesCents:  inv.ChargesCents,
+		CreditsCents:  inv.CreditsCents,
+		PaymentsCents: inv.PaymentsCents,
+		ClosingCents:  inv.ClosingBalanceCents,
+	}
+	for _, l := range inv.Lines {
+		d.Lines = append(d.Lines, invoice.Line{
+			Date:        l.CreatedAt,
+			Kind:        l.Kind,
+			Item:        strPtr(l.Serial),
+			Description: l.Description,
+			AmountCents: l.AmountCents,
+		})
+	}
+	return d
+}
+
+// GET /api/billing/ledger?kind=&from=&to=   我的账本与余额
+func (bc *BillingController) MyLedger(c *gin.Context) {
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	bc.ledger(c, uid)
+}
+
+// GET /api/billing/invoices/:period?format=json|pdf   period = YYYY-MM
+func (bc *BillingController) MyInvoice(c *gin.Context) {
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	bc.invoice(c, uid)
+}
+
+// GET /api/admin/billing/ledger?userId=&kind=&from=&to=   带 userId 时附余额
+func (bc *BillingController) Ledger(c *gin.Context) {
+	bc.ledger(c, c.Query("userId"))
+}
+
+// GET /api/admin/billing/users/:id/invoices/:period?format=json|pdf
+func (bc *BillingController) UserInvoice(c *gin.Context) {
+	bc.invoice(c, c.Param("id"))
+}
+
+// POST /api/admin/billing/payments   {userId, amountCents, method?, note?}  手工登记付款
+func (bc *BillingController) RecordPayment(c *gin.Context) {
+	var in struct {
+		UserID      string `json:"userId" binding:"required"`
+		AmountCents int64  `json:"amountCents" binding:"required"`
+		Method      string `json:"method"`
+		Note        string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	v, _ := c.Get("userID")
+	adminID, _ := v.(string)
+	e, err := bc.Repo.RecordPayment(c.Request.Context(), db.RecordPaymentInput{
+		UserID:      in.UserID,
+		AmountCents: in.AmountCents,
+		Method:      in.Method,
+		Note:        in.Note,
+		ActorID:     adminID,
+	})
+	if err != nil {
+		writeBillingError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, e)
+}
+
+// POST /api/admin/billing/entries/:id/waive   {reason}  减免一笔租金 / 逾期费
+func (bc *BillingController) Waive(c *gin.Context) {
+	var in struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	v, _ := c.Get("userID")
+	adminID, _ := v.(string)
+	e, err := bc.Repo.WaiveCharge(c.Request.Context(), c.Param("id"), in.Reason, adminID)
+	if err != nil {
+		writeBillingError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, e)
+}
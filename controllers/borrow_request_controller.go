@@ -0,0 +1,130 @@
+// controllers/borrow_request_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type BorrowRequestController struct{ *Srv }
+
+func NewBorrowRequestController(s *Srv) *BorrowRequestController {
+	return &BorrowRequestController{Srv: s}
+}
+
+// writeBorrowRequestError 批准相当于一次借出，借出相关错误沿用 writeBorrowError
+func writeBorrowRequestError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, app.H{"error": err.Error()})
+	case errors.Is(err, db.ErrInvalidRequest):
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+	case errors.Is(err, db.ErrRequestPending), errors.Is(err, db.ErrRequestClosed),
+		errors.Is(err, db.ErrItemNotAvailable):
+		c.JSON(http.StatusConflict, app.H{"error": err.Error()})
+	default:
+		writeBorrowError(c, err)
+	}
+}
+
+// submitBorrowRequest 借出被要求审批时改为提交申请；失败已写响应时返回 false
+func (s *Srv) submitBorrowRequest(c *gin.Context, userID, itemID string, dueAt *time.Time, note string) (*models.BorrowRequest, bool) {
+	req, err := s.Repo.CreateBorrowRequest(c.Request.Context(), userID, itemID, dueAt, note)
+	if err != nil {
+		writeBorrowRequestError(c, err)
+		return nil, false
+	}
+	return req, true
+}
+
+// GET /api/borrow-requests?status=   我的借用申请
+func (bc *BorrowRequestController) ListMine(c *gin.Context) {
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	rows, err := bc.Repo.ListBorrowRequests(c.Request.Context(), db.BorrowRequestsQuery{UserID: uid, Status: c.Query("status")})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
+
+// POST /api/borrow-requests/:id/cancel   申请人撤回
+func (bc *BorrowRequestController) Cancel(c *gin.Context) {
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	req, err := bc.Repo.CancelBorrowRequest(c.Request.Context(), c.Param("id"), uid)
+	if err != nil {
+		writeBorrowRequestError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// GET /api/admin/borrow-requests?status=&userId=&itemId=   审批队列，默认只看 pending（先到先处理）
+func (bc *BorrowRequestController) ListAdmin(c *gin.Context) {
+	status := c.DefaultQuery("status", models.RequestPending)
+	if status == "all" {
+		status = ""
+	}
+	rows, err := bc.Repo.ListBorrowRequests(c.Request.Context(), db.BorrowRequestsQuery{
+		UserID: c.Query("userId"),
+		ItemID: c.Query("itemId"),
+		Status: status,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
+
+// POST /api/admin/borrow-requests/:id/approve   {reason?, dueAt?}  批准即借出
+func (bc *BorrowRequestController) Approve(c *gin.Context) {
+	var in struct {
+		Reason string     `json:"reason"`
+		DueAt  *time.Time `json:"dueAt"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+			return
+		}
+	}
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	req, loan, err := bc.Repo.ApproveBorrowRequest(c.Request.Context(), c.Param("id"), uid, in.Reason, in.DueAt)
+	if err != nil {
+		writeBorrowRequestError(c, err)
+		return
+	}
+	_ = bc.Cart.Remove(c.Request.Context(), req.UserID, req.ItemID)
+	c.JSON(http.StatusOK, app.H{"request": req, "loan": loan})
+}
+
+// POST /api/admin/borrow-requests/:id/reject   {reason}
+func (bc *BorrowRequestController) Reject(c *gin.Context) {
+	var in struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	req, err := bc.Repo.RejectBorrowRequest(c.Request.Context(), c.Param("id"), uid, in.Reason)
+	if err != nil {
+		writeBorrowRequestError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
@@ -0,0 +1,199 @@
+// controllers/cart_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/cart"
+	"Gin_postgres_redis_rent_tool/db"
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CartController struct{ *Srv }
+
+func NewCartController(s *Srv) *CartController { return &CartController{Srv: s} }
+
+// writeHeld 物品在别人的购物车里占用着 → 409；err 不是占用时返回 false
+func writeHeld(c *gin.Context, err error) bool {
+	var he *db.HeldError
+	if !errors.As(err, &he) {
+		return false
+	}
+	c.JSON(http.StatusConflict, app.H{"error": he.Error(), "itemId": he.Hold.ItemID, "heldUntil": he.Hold.ExpiresAt})
+	return true
+}
+
+// attachHolds 管理员物品列表标出被购物车占用的物品
+func (s *Srv) attachHolds(c *gin.Context, rows []db.AdminItemRow) error {
+	ids := make([]string, len(rows))
+	for i, r := range rows {
+		ids[i] = r.ID
+	}
+	holds, err := s.Cart.Holds(c.Request.Context(), ids)
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		if h, ok := holds[rows[i].ID]; ok {
+			rows[i].HeldBy = &h.UserID
+			rows[i].HeldUntil = &h.ExpiresAt
+		}
+	}
+	return nil
+}
+
+type cartEntry struct {
+	Item      models.Item `json:"item"`
+	ExpiresAt time.Time   `json:"expiresAt"`
+}
+
+// GET /api/cart   购物车中仍在占用期内的物品
+func (cc *CartController) Get(c *gin.Context) {
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	holds, err := cc.Cart.Items(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	ids := make([]string, len(holds))
+	for i, h := range holds {
+		ids[i] = h.ItemID
+	}
+	items, err := cc.Repo.FindItemsByIDs(c.Request.Context(), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	byID := map[string]models.Item{}
+	for _, it := range items {
+		byID[it.ID] = it
+	}
+	out := make([]cartEntry, 0, len(holds))
+	for _, h := range holds {
+		if it, ok := byID[h.ItemID]; ok {
+			out = append(out, cartEntry{Item: it, ExpiresAt: h.ExpiresAt})
+		}
+	}
+	c.JSON(http.StatusOK, app.H{"items": out})
+}
+
+// POST /api/cart/items   {itemId}  放入购物车并占用；已在车里则续期
+func (cc *CartController) Add(c *gin.Context) {
+	var in struct {
+		ItemID string `json:"itemId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	it, err := cc.Repo.FindItemByID(c.Request.Context(), in.ItemID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, app.H{"error": "item not found"})
+		return
+	}
+	if it.Status != models.ItemStatusActive || it.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, app.H{"error": db.ErrItemNotAvailable.Error()})
+		return
+	}
+	if it.InUse {
+		c.JSON(http.StatusConflict, app.H{"error": "already borrowed", "canJoinWaitlist": true})
+		return
+	}
+	// 需审批的物品不能进购物车自助结账，只能单独提交借用申请
+	pol, err := cc.Repo.EffectiveLoanPolicy(c.Request.Context(), it.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	if pol.RequiresApproval {
+		c.JSON(http.StatusConflict, app.H{"error": db.ErrApprovalRequired.Error(), "approvalRequired": true})
+		return
+	}
+	h, err := cc.Cart.Add(c.Request.Context(), uid, it.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, cart.ErrHeld):
+			c.JSON(http.StatusConflict, app.H{"error": err.Error()})
+		case errors.Is(err, cart.ErrCartFull):
+			c.JSON(http.StatusBadRequest, app.H{"error": err.Error(), "max": cart.MaxItems})
+		default:
+			c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, cartEntry{Item: *it, ExpiresAt: h.ExpiresAt})
+}
+
+// DELETE /api/cart/items/:itemId   移出并释放占用
+func (cc *CartController) Remove(c *gin.Context) {
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	if err := cc.Cart.Remove(c.Request.Context(), uid, c.Param("itemId")); err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
+
+// DELETE /api/cart   清空
+func (cc *CartController) Clear(c *gin.Context) {
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	holds, err := cc.Cart.Items(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	for _, h := range holds {
+		if err := cc.Cart.Remove(c.Request.Context(), uid, h.ItemID); err != nil {
+			c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
+
+// POST /api/cart/checkout   {dueAt?, note?}  购物车里的物品在一个事务里全部借出，任一件不可借则都不借
+func (cc *CartController) Checkout(c *gin.Context) {
+	var in struct {
+		DueAt *time.Time `json:"dueAt"`
+		Note  string     `json:"note"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+			return
+		}
+	}
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	holds, err := cc.Cart.Items(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	if len(holds) == 0 {
+		c.JSON(http.StatusBadRequest, app.H{"error": "cart is empty"})
+		return
+	}
+	ids := make([]string, len(holds))
+	for i, h := range holds {
+		ids[i] = h.ItemID
+	}
+	loans, err := cc.Repo.BorrowItems(c.Request.Context(), uid, ids, in.DueAt, in.Note)
+	if err != nil {
+		writeBatchBorrowError(c, err)
+		return
+	}
+	// 已借出，释放占用；失败也不影响结果，占用到期会自行消失
+	_ = cc.Cart.Remove(c.Request.Context(), uid, ids...)
+	c.JSON(http.StatusCreated, app.H{"items": loans})
+}
@@ -0,0 +1,139 @@
+// controllers/category_attribute_controller.go
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type attributeDefReq struct {
+	Name       string   `json:"name"`
+	Label      string   `json:"label"`
+	Type       string   `json:"type" binding:"required"`
+	Required   bool     `json:"required"`
+	EnumValues []string `json:"enumValues"`
+}
+
+// bindAttrFilters 解析 attr.<key>=、attrMin.<key>=、attrMax.<key>= 查询参数
+func bindAttrFilters(c *gin.Context, q *db.AdminItemsQuery) error {
+	for k, vs := range c.Request.URL.Query() {
+		if len(vs) == 0 {
+			continue
+		}
+		prefix, key, ok := strings.Cut(k, ".")
+		if !ok {
+			continue
+		}
+		v := strings.TrimSpace(vs[0])
+		switch prefix {
+		case "attr":
+			if q.Attrs == nil {
+				q.Attrs = map[string]string{}
+			}
+			q.Attrs[key] = v
+		case "attrMin", "attrMax":
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("%s must be a number", k)
+			}
+			if prefix == "attrMin" {
+				if q.AttrMin == nil {
+					q.AttrMin = map[string]float64{}
+				}
+				q.AttrMin[key] = f
+			} else {
+				if q.AttrMax == nil {
+					q.AttrMax = map[string]float64{}
+				}
+				q.AttrMax[key] = f
+			}
+		}
+	}
+	return nil
+}
+
+// GET /api/categories/:id/attributes   有效属性定义（含从上级继承的）
+func (cc *CategoryController) AttributeSchema(c *gin.Context) {
+	defs, err := cc.Repo.CategoryAttributeSchema(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": defs})
+}
+
+// POST /api/admin/categories/:id/attributes   {name, label, type, required, enumValues}
+func (cc *CategoryController) CreateAttribute(c *gin.Context) {
+	var req attributeDefReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	d := &models.CategoryAttribute{
+		CategoryID: c.Param("id"),
+		Name:       req.Name,
+		Label:      req.Label,
+		Type:       req.Type,
+		Required:   req.Required,
+		EnumValues: req.EnumValues,
+	}
+	if err := cc.Repo.CreateCategoryAttribute(c.Request.Context(), d); err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, d)
+}
+
+// PUT /api/admin/category-attributes/:id   键名不可改
+func (cc *CategoryController) UpdateAttribute(c *gin.Context) {
+	var req attributeDefReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	d, err := cc.Repo.UpdateCategoryAttribute(c.Request.Context(), c.Param("id"), models.CategoryAttribute{
+		Label:      req.Label,
+		Type:       req.Type,
+		Required:   req.Required,
+		EnumValues: req.EnumValues,
+	})
+	if err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// DELETE /api/admin/category-attributes/:id   已有物品上的值保留
+func (cc *CategoryController) DeleteAttribute(c *gin.Context) {
+	if err := cc.Repo.DeleteCategoryAttribute(c.Request.Context(), c.Param("id")); err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
+
+// PUT /api/admin/items/:id/attributes   {attributes: {...}}  整体替换，按分类 schema 校验
+func (cc *CategoryController) SetItemAttributes(c *gin.Context) {
+	var in struct {
+		Attributes map[string]any `json:"attributes"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	attrs, err := cc.Repo.SetItemAttributes(c.Request.Context(), c.Param("id"), in.Attributes)
+	if err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"attributes": attrs})
+}
@@ -0,0 +1,141 @@
+// controllers/category_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CategoryController struct{ *Srv }
+
+func NewCategoryController(s *Srv) *CategoryController { return &CategoryController{Srv: s} }
+
+func categoryErrStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrInvalidCategory), errors.Is(err, db.ErrInvalidAttributeDef):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrInvalidAttributes):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, db.ErrCategoryExists), errors.Is(err, db.ErrCategoryCycle), errors.Is(err, db.ErrCategoryNotEmpty),
+		errors.Is(err, db.ErrAttributeExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeCategoryError 属性校验失败时附带逐条问题
+func writeCategoryError(c *gin.Context, err error) {
+	body := app.H{"error": err.Error()}
+	var ae *db.AttributeError
+	if errors.As(err, &ae) {
+		body = app.H{"error": db.ErrInvalidAttributes.Error(), "problems": ae.Problems}
+	}
+	c.JSON(categoryErrStatus(err), body)
+}
+
+type categoryReq struct {
+	Name     string  `json:"name" binding:"required"`
+	ParentID *string `json:"parentId"`
+}
+
+// GET /api/categories   扁平列表，带路径和物品数
+func (cc *CategoryController) List(c *gin.Context) {
+	rows, err := cc.Repo.ListCategories(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
+
+// POST /api/admin/categories   {name, parentId?}
+func (cc *CategoryController) Create(c *gin.Context) {
+	var req categoryReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	cat := &models.Category{Name: req.Name, ParentID: req.ParentID}
+	if err := cc.Repo.CreateCategory(c.Request.Context(), cat); err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, cat)
+}
+
+// PUT /api/admin/categories/:id   {name, parentId?}  改名 / 移动
+func (cc *CategoryController) Update(c *gin.Context) {
+	var req categoryReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	cat, err := cc.Repo.UpdateCategory(c.Request.Context(), c.Param("id"), req.Name, req.ParentID)
+	if err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cat)
+}
+
+// DELETE /api/admin/categories/:id   其下物品归到上级分类
+func (cc *CategoryController) Delete(c *gin.Context) {
+	if err := cc.Repo.DeleteCategory(c.Request.Context(), c.Param("id")); err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
+
+// PUT /api/admin/items/:id/category   {categoryId: null 表示取消分类}
+func (cc *CategoryController) SetItemCategory(c *gin.Context) {
+	var in struct {
+		CategoryID *string `json:"categoryId"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	if err := cc.Repo.SetItemCategory(c.Request.Context(), c.Param("id"), in.CategoryID); err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true, "categoryId": in.CategoryID})
+}
+
+// PUT /api/admin/items/:id/tags   {tags: [...]}  整体替换
+func (cc *CategoryController) SetItemTags(c *gin.Context) {
+	var in struct {
+		Tags []string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	tags, err := cc.Repo.SetItemTags(c.Request.Context(), c.Param("id"), in.Tags)
+	if err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"tags": tags})
+}
+
+// GET /api/tags?prefix=   在用标签及次数
+func (cc *CategoryController) ListTags(c *gin.Context) {
+	rows, err := cc.Repo.ListTags(c.Request.Context(), c.Query("prefix"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
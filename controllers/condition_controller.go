@@ -0,0 +1,177 @@
+// controllers/condition_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ConditionController struct{ *Srv }
+
+func NewConditionController(s *Srv) *ConditionController { return &ConditionController{Srv: s} }
+
+func conditionErrStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrInvalidChecklist), errors.Is(err, db.ErrInvalidCondition):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrConditionRecorded):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// loanForUser 借用人本人或管理员；出错时已写响应，返回 nil
+func (cc *ConditionController) loanForUser(c *gin.Context) *models.Loan {
+	loan, err := cc.Repo.FindLoanByID(c.Request.Context(), c.Param("loanId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, app.H{"error": "loan not found"})
+		return nil
+	}
+	v, _ := c.Get("userID")
+	if uid, _ := v.(string); uid != loan.UserID && !c.GetBool("isAdmin") {
+		c.JSON(http.StatusForbidden, app.H{"error": "forbidden"})
+		return nil
+	}
+	return loan
+}
+
+// GET /api/items/:id/checklist   借还时需要清点的配件（含分类继承）
+func (cc *ConditionController) ItemChecklist(c *gin.Context) {
+	cs, err := cc.Repo.ItemChecklist(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(conditionErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": cs})
+}
+
+// GET /api/items/loans/:loanId/condition   借出 / 归还状况及照片
+func (cc *ConditionController) LoanConditions(c *gin.Context) {
+	loan := cc.loanForUser(c)
+	if loan == nil {
+		return
+	}
+	rows, err := cc.Repo.ListConditionReports(c.Request.Context(), db.ConditionReportsQuery{LoanID: loan.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	as, err := cc.Repo.ListAttachments(c.Request.Context(), models.AttachOwnerLoan, loan.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	photos := map[string][]models.Attachment{models.ConditionAtCheckout: {}, models.ConditionAtReturn: {}}
+	for _, a := range as {
+		switch a.Kind {
+		case models.AttachKindCheckout:
+			photos[models.ConditionAtCheckout] = append(photos[models.ConditionAtCheckout], a)
+		case models.AttachKindReturn:
+			photos[models.ConditionAtReturn] = append(photos[models.ConditionAtReturn], a)
+		}
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows, "photos": photos})
+}
+
+// POST /api/items/loans/:loanId/condition   {stage, grade, damage?, checklist}  借出后或归还后补记
+func (cc *ConditionController) RecordCondition(c *gin.Context) {
+	loan := cc.loanForUser(c)
+	if loan == nil {
+		return
+	}
+	var in struct {
+		Stage string `json:"stage" binding:"required"`
+		db.ConditionInput
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	rep, err := cc.Repo.RecordCondition(c.Request.Context(), loan.ID, in.Stage, in.ConditionInput, uid)
+	if err != nil {
+		c.JSON(conditionErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, rep)
+}
+
+// GET /api/admin/condition-reports?itemId=&flagged=true
+func (cc *ConditionController) ListReports(c *gin.Context) {
+	rows, err := cc.Repo.ListConditionReports(c.Request.Context(), db.ConditionReportsQuery{
+		ItemID:      c.Query("itemId"),
+		FlaggedOnly: c.Query("flagged") == "true",
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
+
+type checklistItemReq struct {
+	Scope    string `json:"scope"`   // category / item，仅新建时使用
+	ScopeID  string `json:"scopeId"` // 仅新建时使用
+	Label    string `json:"label" binding:"required"`
+	Position int    `json:"position"`
+}
+
+// GET /api/admin/checklist-items?scope=category|item&scopeId=   只列自己定义的，不含继承
+func (cc *ConditionController) ListChecklistItems(c *gin.Context) {
+	cs, err := cc.Repo.ListChecklistItems(c.Request.Context(), c.Query("scope"), c.Query("scopeId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": cs})
+}
+
+// POST /api/admin/checklist-items   {scope, scopeId, label, position?}
+func (cc *ConditionController) CreateChecklistItem(c *gin.Context) {
+	var req checklistItemReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	ci := &models.ChecklistItem{Scope: req.Scope, ScopeID: req.ScopeID, Label: req.Label, Position: req.Position}
+	if err := cc.Repo.CreateChecklistItem(c.Request.Context(), ci); err != nil {
+		c.JSON(conditionErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, ci)
+}
+
+// PUT /api/admin/checklist-items/:id   {label, position?}
+func (cc *ConditionController) UpdateChecklistItem(c *gin.Context) {
+	var req checklistItemReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	ci, err := cc.Repo.UpdateChecklistItem(c.Request.Context(), c.Param("id"), req.Label, req.Position)
+	if err != nil {
+		c.JSON(conditionErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ci)
+}
+
+// DELETE /api/admin/checklist-items/:id
+func (cc *ConditionController) DeleteChecklistItem(c *gin.Context) {
+	if err := cc.Repo.DeleteChecklistItem(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(conditionErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
@@ -0,0 +1,131 @@
+// controllers/export_controller.go
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+	"Gin_postgres_redis_rent_tool/export"
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportController struct{ *Srv }
+
+func NewExportController(s *Srv) *ExportController { return &ExportController{Srv: s} }
+
+func fmtTime(t time.Time) string { return t.UTC().Format(time.RFC3339) }
+
+func fmtTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return fmtTime(*t)
+}
+
+func strPtr(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func fmtAttrs(m models.JSONMap) string {
+	if len(m) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+// stream 写响应头后逐行导出；一旦开始写就无法再改状态码，中途出错只能记日志并截断
+func (ec *ExportController) stream(c *gin.Context, name string, header []any, run func(export.Writer) error) {
+	format := c.DefaultQuery("format", "csv")
+	f, ok := export.Formats[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, app.H{"error": "format must be csv or xlsx"})
+		return
+	}
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().UTC().Format("20060102-150405"), f.Ext)
+	c.Header("Content-Type", f.ContentType)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	w, err := export.New(format, c.Writer, name)
+	if err == nil {
+		err = w.WriteRow(header)
+	}
+	if err == nil {
+		err = run(w)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		log.Printf("[export] %s: %v", name, err)
+	}
+}
+
+// GET /api/admin/export/items?format=csv|xlsx&q=&status=&itemStatus=&categoryId=&tag=&archived=&locationId=&attr.<key>=&attrMin.<key>=&attrMax.<key>=
+func (ec *ExportController) Items(c *gin.Context) {
+	q, err := adminItemsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	header := []any{"id", "serial", "name", "category", "status", "in_use", "borrower_username", "borrower_display_name",
+		"borrowed_at", "due_at", "overdue", "created_at", "attributes", "current_location", "home_location"}
+	ec.stream(c, "items", header, func(w export.Writer) error {
+		return ec.Repo.EachAdminItem(c.Request.Context(), q, func(r *db.AdminItemRow) error {
+			return w.WriteRow([]any{
+				r.ID, r.Serial, r.Name, strPtr(r.CategoryName), r.Status, r.InUse, strPtr(r.BorrowerUsername), strPtr(r.BorrowerDisplayName),
+				fmtTimePtr(r.BorrowedAt), fmtTimePtr(r.DueAt), r.Overdue, fmtTime(r.CreatedAt), fmtAttrs(r.Attributes),
+				strPtr(r.CurrentLocationName), strPtr(r.HomeLocationName),
+			})
+		})
+	})
+}
+
+// GET /api/admin/export/loans?format=&status=open|returned|overdue&outcome=&userId=&itemId=&categoryId=
+// outcome = returned|handed_over|lost|stolen|destroyed
+func (ec *ExportController) Loans(c *gin.Context) {
+	q := db.LoansExportQuery{
+		UserID:     c.Query("userId"),
+		ItemID:     c.Query("itemId"),
+		Status:     c.Query("status"),
+		Outcome:    c.Query("outcome"),
+		CategoryID: c.Query("categoryId"),
+	}
+	header := []any{"id", "item_id", "serial", "item_name", "category", "user_id", "username",
+		"borrowed_at", "due_at", "returned_at", "returned_by", "outcome", "renewal_count", "note"}
+	ec.stream(c, "loans", header, func(w export.Writer) error {
+		return ec.Repo.EachLoan(c.Request.Context(), q,
+			func(r *db.LoanExportRow) error {
+				return w.WriteRow([]any{
+					r.ID, r.ItemID, r.Serial, r.ItemName, strPtr(r.CategoryName), r.UserID, r.Username,
+					fmtTime(r.BorrowedAt), fmtTimePtr(r.DueAt), fmtTimePtr(r.ReturnedAt), strPtr(r.ReturnedUsername),
+					r.Outcome, r.RenewalCount, r.Note,
+				})
+			})
+	})
+}
+
+// GET /api/admin/export/users?format=&q=
+func (ec *ExportController) Users(c *gin.Context) {
+	header := []any{"id", "username", "display_name", "is_admin", "created_at", "last_seen_at",
+		"open_loans", "overdue_loans", "open_serials"}
+	ec.stream(c, "users", header, func(w export.Writer) error {
+		return ec.Repo.EachUserWithOpenLoans(c.Request.Context(), c.Query("q"), func(r *db.UserExportRow) error {
+			return w.WriteRow([]any{
+				r.ID, r.Username, r.DisplayName, r.IsAdmin, fmtTime(r.CreatedAt), fmtTimePtr(r.LastSeenAt),
+				r.OpenLoans, r.OverdueLoans, r.OpenSerials,
+			})
+		})
+	})
+}
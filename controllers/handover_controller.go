@@ -0,0 +1,135 @@
+// controllers/handover_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type HandoverController struct{ *Srv }
+
+func NewHandoverController(s *Srv) *HandoverController { return &HandoverController{Srv: s} }
+
+// writeHandoverError 接受转交相当于一次借出，借出相关错误沿用 writeBorrowError
+func writeHandoverError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, app.H{"error": err.Error()})
+	case errors.Is(err, db.ErrHandoverForbidden):
+		c.JSON(http.StatusForbidden, app.H{"error": err.Error()})
+	case errors.Is(err, db.ErrHandoverNotAllowed), errors.Is(err, db.ErrHandoverPending),
+		errors.Is(err, db.ErrHandoverClosed), errors.Is(err, db.ErrItemNotAvailable):
+		c.JSON(http.StatusConflict, app.H{"error": err.Error()})
+	default:
+		writeBorrowError(c, err)
+	}
+}
+
+// POST /api/items/loans/:loanId/handover   {toUsername, note?}  借用人发起转交，等对方确认
+func (hc *HandoverController) Start(c *gin.Context) {
+	var in struct {
+		ToUsername string `json:"toUsername" binding:"required"`
+		Note       string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	to, err := hc.Repo.FindUserIDByUsername(c.Request.Context(), in.ToUsername)
+	if err != nil {
+		c.JSON(http.StatusNotFound, app.H{"error": err.Error()})
+		return
+	}
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	h, err := hc.Repo.StartHandover(c.Request.Context(), db.StartHandoverInput{
+		LoanID:   c.Param("loanId"),
+		ActorID:  uid,
+		ByAdmin:  c.GetBool("isAdmin"),
+		ToUserID: to.ID,
+		Note:     in.Note,
+	})
+	if err != nil {
+		writeHandoverError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, h)
+}
+
+// GET /api/handovers?status=pending   我发起的和转给我的
+func (hc *HandoverController) ListMine(c *gin.Context) {
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	rows, err := hc.Repo.ListHandovers(c.Request.Context(), db.HandoversQuery{UserID: uid, Status: c.Query("status")})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
+
+// POST /api/handovers/:id/accept   {dueAt?}  接收人确认，旧借用关闭、新借用开始
+func (hc *HandoverController) Accept(c *gin.Context) {
+	var in struct {
+		DueAt *time.Time `json:"dueAt"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+			return
+		}
+	}
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	h, loan, err := hc.Repo.AcceptHandover(c.Request.Context(), c.Param("id"), uid, in.DueAt)
+	if err != nil {
+		writeHandoverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"handover": h, "loan": loan})
+}
+
+// POST /api/handovers/:id/decline   接收人拒绝
+func (hc *HandoverController) Decline(c *gin.Context) {
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	h, err := hc.Repo.DeclineHandover(c.Request.Context(), c.Param("id"), uid)
+	if err != nil {
+		writeHandoverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h)
+}
+
+// POST /api/handovers/:id/cancel   发起人或管理员撤回
+func (hc *HandoverController) Cancel(c *gin.Context) {
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	h, err := hc.Repo.CancelHandover(c.Request.Context(), c.Param("id"), uid, c.GetBool("isAdmin"))
+	if err != nil {
+		writeHandoverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h)
+}
+
+// GET /api/admin/handovers?userId=&itemId=&status=
+func (hc *HandoverController) ListAdmin(c *gin.Context) {
+	rows, err := hc.Repo.ListHandovers(c.Request.Context(), db.HandoversQuery{
+		UserID: c.Query("userId"),
+		ItemID: c.Query("itemId"),
+		Status: c.Query("status"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
@@ -0,0 +1,134 @@
+// controllers/item_edit_controller.go
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// itemETag 以版本号作为 ETag
+func itemETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersion 解析 If-Match；"*" 表示不校验（返回 0）。缺失或格式不对时已写响应
+func ifMatchVersion(c *gin.Context) (int64, bool) {
+	h := strings.TrimSpace(c.GetHeader("If-Match"))
+	if h == "" {
+		c.JSON(http.StatusPreconditionRequired, app.H{"error": "If-Match header with the item ETag is required"})
+		return 0, false
+	}
+	if h == "*" {
+		return 0, true
+	}
+	h = strings.Trim(strings.TrimPrefix(h, "W/"), `"`)
+	v, err := strconv.ParseInt(h, 10, 64)
+	if err != nil || v <= 0 {
+		c.JSON(http.StatusBadRequest, app.H{"error": "invalid If-Match"})
+		return 0, false
+	}
+	return v, true
+}
+
+func writeItemEditError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, app.H{"error": "item not found"})
+	case errors.Is(err, db.ErrVersionConflict):
+		c.JSON(http.StatusPreconditionFailed, app.H{"error": err.Error()})
+	case errors.Is(err, db.ErrInvalidItem):
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+	case errors.Is(err, db.ErrSerialTaken), errors.Is(err, db.ErrItemHasOpenLoan), errors.Is(err, db.ErrItemArchived):
+		c.JSON(http.StatusConflict, app.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+	}
+}
+
+// PATCH /api/admin/items/:id   {name?, serial?}   需带 If-Match
+func (ic *ItemController) UpdateItem(c *gin.Context) {
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	var in struct {
+		Name   *string `json:"name"`
+		Serial *string `json:"serial"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	it, err := ic.Repo.UpdateItem(c.Request.Context(), db.UpdateItemInput{
+		ItemID:  c.Param("id"),
+		Version: version,
+		Name:    in.Name,
+		Serial:  in.Serial,
+	})
+	if err != nil {
+		writeItemEditError(c, err)
+		return
+	}
+	c.Header("ETag", itemETag(it.Version))
+	c.JSON(http.StatusOK, it)
+}
+
+// POST /api/admin/items/:id/archive   需带 If-Match；有未归还借用时拒绝
+func (ic *ItemController) ArchiveItem(c *gin.Context) {
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	v, _ := c.Get("userID")
+	adminID, _ := v.(string)
+	it, err := ic.Repo.ArchiveItem(c.Request.Context(), c.Param("id"), version, adminID)
+	if err != nil {
+		writeItemEditError(c, err)
+		return
+	}
+	c.Header("ETag", itemETag(it.Version))
+	c.JSON(http.StatusOK, it)
+}
+
+// POST /api/admin/items/:id/unarchive   需带 If-Match
+func (ic *ItemController) UnarchiveItem(c *gin.Context) {
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	it, err := ic.Repo.UnarchiveItem(c.Request.Context(), c.Param("id"), version)
+	if err != nil {
+		writeItemEditError(c, err)
+		return
+	}
+	c.Header("ETag", itemETag(it.Version))
+	c.JSON(http.StatusOK, it)
+}
+
+// DELETE /api/admin/items/:id   需带 If-Match；连同借用历史一并删除，只想下架请用归档
+func (ic *ItemController) DeleteItem(c *gin.Context) {
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	atts, err := ic.Repo.DeleteItem(c.Request.Context(), c.Param("id"), version)
+	if err != nil {
+		writeItemEditError(c, err)
+		return
+	}
+	// 库里的记录已删，文件清理失败只记日志
+	ac := NewAttachmentController(ic.Srv)
+	for i := range atts {
+		ac.removeBlobs(context.WithoutCancel(c.Request.Context()), &atts[i])
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
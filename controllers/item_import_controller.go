@@ -0,0 +1,206 @@
+// controllers/item_import_controller.go
+package controllers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 单次导入最多行数
+const maxImportRows = 5000
+
+// csvTable 带表头的 CSV：列名不区分大小写
+type csvTable struct {
+	r      *csv.Reader
+	header []string
+	col    map[string]int
+}
+
+func openCSV(r io.Reader, required ...string) (*csvTable, error) {
+	br := bufio.NewReader(r)
+	// Excel 导出的 CSV 常带 UTF-8 BOM
+	if b, err := br.Peek(3); err == nil && string(b) == "\xEF\xBB\xBF" {
+		_, _ = br.Discard(3)
+	}
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	t := &csvTable{r: cr, header: header, col: map[string]int{}}
+	for i, h := range header {
+		t.col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, need := range required {
+		if _, ok := t.col[need]; !ok {
+			return nil, fmt.Errorf("missing column %q", need)
+		}
+	}
+	return t, nil
+}
+
+func (t *csvTable) get(rec []string, name string) string {
+	if i, ok := t.col[name]; ok && i < len(rec) {
+		return rec[i]
+	}
+	return ""
+}
+
+// each 逐行回调（跳过空行），line 为文件行号（表头是第 1 行）
+func (t *csvTable) each(fn func(line int, rec []string) error) error {
+	n := 0
+	for line := 2; ; line++ {
+		rec, err := t.r.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		blank := true
+		for _, f := range rec {
+			if strings.TrimSpace(f) != "" {
+				blank = false
+				break
+			}
+		}
+		if blank {
+			continue
+		}
+		if n++; n > maxImportRows {
+			return fmt.Errorf("too many rows (max %d)", maxImportRows)
+		}
+		if err := fn(line, rec); err != nil {
+			return err
+		}
+	}
+}
+
+// parseItemCSV 表头必须含 serial、name，可选 status、category；其余列作为扩展属性（空值忽略）
+func parseItemCSV(r io.Reader) ([]db.ItemImportRow, error) {
+	t, err := openCSV(r, "serial", "name")
+	if err != nil {
+		return nil, err
+	}
+	var rows []db.ItemImportRow
+	err = t.each(func(line int, rec []string) error {
+		row := db.ItemImportRow{
+			Line:     line,
+			Serial:   t.get(rec, "serial"),
+			Name:     t.get(rec, "name"),
+			Status:   t.get(rec, "status"),
+			Category: t.get(rec, "category"),
+		}
+		for i, h := range t.header {
+			key := strings.TrimSpace(h)
+			switch strings.ToLower(key) {
+			case "serial", "name", "status", "category":
+				continue
+			}
+			if i < len(rec) && key != "" && strings.TrimSpace(rec[i]) != "" {
+				if row.Attributes == nil {
+					row.Attributes = map[string]string{}
+				}
+				row.Attributes[key] = strings.TrimSpace(rec[i])
+			}
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// parseUploadedCSV 读取 multipart 的 file 字段并解析；出错时已写响应
+func parseUploadedCSV[T any](c *gin.Context, parse func(io.Reader) ([]T, error)) ([]T, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadBytes())
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": "missing file"})
+		return nil, false
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return nil, false
+	}
+	defer f.Close()
+
+	rows, err := parse(f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return nil, false
+	}
+	return rows, true
+}
+
+// POST /api/admin/items/import?dryRun=true   multipart: file（CSV）
+func (ic *ItemController) ImportItems(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dryRun", "false"))
+	rows, ok := parseUploadedCSV(c, parseItemCSV)
+	if !ok {
+		return
+	}
+	rep, err := ic.Repo.ImportItems(c.Request.Context(), rows, dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rep)
+}
+
+// parseLoanCSV 表头：serial, username, borrowed_at, due_at, returned_at, note（后三列可选）
+func parseLoanCSV(r io.Reader) ([]db.LoanImportRow, error) {
+	t, err := openCSV(r, "serial", "username", "borrowed_at")
+	if err != nil {
+		return nil, err
+	}
+	var rows []db.LoanImportRow
+	err = t.each(func(line int, rec []string) error {
+		rows = append(rows, db.LoanImportRow{
+			Line:       line,
+			Serial:     t.get(rec, "serial"),
+			Username:   t.get(rec, "username"),
+			BorrowedAt: t.get(rec, "borrowed_at"),
+			DueAt:      t.get(rec, "due_at"),
+			ReturnedAt: t.get(rec, "returned_at"),
+			Note:       t.get(rec, "note"),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// POST /api/admin/loans/import?dryRun=true   multipart: file（CSV）导入历史借用记录
+func (ic *ItemController) ImportLoans(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dryRun", "false"))
+	rows, ok := parseUploadedCSV(c, parseLoanCSV)
+	if !ok {
+		return
+	}
+	rep, err := ic.Repo.ImportLoans(c.Request.Context(), rows, dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rep)
+}
@@ -2,6 +2,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type ItemController struct{ *Srv }
@@ -21,24 +23,35 @@ func NewItemController(s *Srv) *ItemController { return &ItemController{Srv: s}
 // 管理员创建一件唯一物品
 func (ic *ItemController) CreateItem(c *gin.Context) {
 	var in struct {
-		Name   string `json:"name" binding:"required"`
-		Serial string `json:"serial" binding:"required"`
+		Name       string         `json:"name" binding:"required"`
+		Serial     string         `json:"serial" binding:"required"`
+		CategoryID *string        `json:"categoryId"`
+		Tags       []string       `json:"tags"`
+		Attributes models.JSONMap `json:"attributes"`
 	}
 	if err := c.ShouldBindJSON(&in); err != nil {
 		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
 		return
 	}
-	it := &models.Item{ID: uuid.NewString(), Name: in.Name, Serial: in.Serial}
+	it := &models.Item{ID: uuid.NewString(), Name: in.Name, Serial: in.Serial, CategoryID: in.CategoryID, Tags: in.Tags, Attributes: in.Attributes}
 	if err := ic.Repo.CreateItem(c.Request.Context(), it); err != nil {
+		if errors.Is(err, db.ErrInvalidCategory) || errors.Is(err, db.ErrInvalidAttributes) {
+			writeCategoryError(c, err)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
 		return
 	}
+	c.Header("ETag", itemETag(it.Version))
 	c.JSON(http.StatusCreated, it)
 }
 
-// 列表（含是否可借）
+// 列表（含是否可借）?categoryId=&tag=
 func (ic *ItemController) ListItems(c *gin.Context) {
-	items, err := ic.Repo.ListItems(c.Request.Context())
+	items, err := ic.Repo.ListItems(c.Request.Context(), db.ItemsQuery{
+		CategoryID: c.Query("categoryId"),
+		Tag:        c.Query("tag"),
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
 		return
@@ -47,6 +60,26 @@ func (ic *ItemController) ListItems(c *gin.Context) {
 	c.JSON(http.StatusOK, app.H{"items": items})
 }
 
+// 物品详情（含手册/照片/证书等附件）
+func (ic *ItemController) GetItem(c *gin.Context) {
+	it, err := ic.Repo.FindItemByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, app.H{"error": "item not found"})
+		return
+	}
+	if it.Tags, err = ic.Repo.ItemTags(c.Request.Context(), it.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	as, err := ic.Repo.ListAttachments(c.Request.Context(), models.AttachOwnerItem, it.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", itemETag(it.Version))
+	c.JSON(http.StatusOK, app.H{"item": it, "attachments": as})
+}
+
 // 借出
 func (ic *ItemController) Borrow(c *gin.Context) {
 	itemID := c.Param("id")
@@ -62,24 +95,62 @@ func (ic *ItemController) Borrow(c *gin.Context) {
 	userID, _ := v.(string)
 
 	var in struct {
-		DueAt *time.Time `json:"dueAt"`
-		Note  string     `json:"note"`
+		DueAt     *time.Time         `json:"dueAt"`
+		Note      string             `json:"note"`
+		Condition *db.ConditionInput `json:"condition"` // 可选：借出时的状况
 	}
 	_ = c.ShouldBindJSON(&in)
 
-	loan, err := ic.Repo.BorrowItem(c.Request.Context(), userID, itemID, in.DueAt, in.Note)
-	if err != nil {
-		if err == db.ErrAlreadyBorrowed {
-			c.JSON(409, app.H{"error": "already borrowed"})
-			return
+	loan, err := ic.Repo.BorrowItem(c.Request.Context(), userID, itemID, in.DueAt, in.Note, in.Condition)
+	if errors.Is(err, db.ErrApprovalRequired) {
+		// 需审批的物品：转为借用申请，等管理员批准
+		if req, ok := ic.submitBorrowRequest(c, userID, itemID, in.DueAt, in.Note); ok {
+			c.JSON(http.StatusAccepted, app.H{"approvalRequired": true, "request": req})
 		}
-		c.JSON(500, app.H{"error": err.Error()})
 		return
 	}
+	if err != nil {
+		writeBorrowError(c, err)
+		return
+	}
+	_ = ic.Cart.Remove(c.Request.Context(), userID, itemID) // 在自己购物车里的顺手移出
 	c.JSON(http.StatusCreated, loan)
 }
 
-// 归还
+// BorrowItem 的错误 → HTTP（借出与扫码借出共用）
+func writeBorrowError(c *gin.Context, err error) {
+	if errors.Is(err, db.ErrAlreadyBorrowed) {
+		c.JSON(409, app.H{"error": "already borrowed", "canJoinWaitlist": true})
+		return
+	}
+	if errors.Is(err, db.ErrClaimedByOther) {
+		c.JSON(409, app.H{"error": err.Error(), "canJoinWaitlist": true})
+		return
+	}
+	if errors.Is(err, db.ErrApprovalRequired) {
+		c.JSON(409, app.H{"error": err.Error(), "approvalRequired": true})
+		return
+	}
+	if errors.Is(err, db.ErrReservationConflict) || errors.Is(err, db.ErrMaintenanceDue) ||
+		errors.Is(err, db.ErrItemNotAvailable) {
+		c.JSON(409, app.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, app.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, db.ErrInvalidCondition) {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	if writeHeld(c, err) || writePolicyViolation(c, err) {
+		return
+	}
+	c.JSON(500, app.H{"error": err.Error()})
+}
+
+// 归还   body 可选：{locationId, condition}  locationId 不填则回到归位处
 func (ic *ItemController) Return(c *gin.Context) {
 	loanID := c.Param("loanId")
 	if loanID == "" {
@@ -92,9 +163,23 @@ func (ic *ItemController) Return(c *gin.Context) {
 		return
 	}
 	userID, _ := v.(string)
+	var in struct {
+		LocationID *string            `json:"locationId"`
+		Condition  *db.ConditionInput `json:"condition"` // 可选：归还时的状况
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+			return
+		}
+	}
 
-	loan, err := ic.Repo.ReturnLoan(c.Request.Context(), loanID, userID)
+	loan, err := ic.Repo.ReturnLoan(c.Request.Context(), loanID, userID, in.LocationID, in.Condition)
 	if err != nil {
+		if errors.Is(err, db.ErrInvalidLocation) || errors.Is(err, db.ErrInvalidCondition) {
+			c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+			return
+		}
 		c.JSON(500, app.H{"error": err.Error()})
 		return
 	}
@@ -144,10 +229,26 @@ func (ic *ItemController) ListMyOpenLoans(c *gin.Context) {
 	})
 }
 
-func (ic *ItemController) ListItemsAdmin(c *gin.Context) {
+// adminItemsFilter 解析 /api/admin/items 的过滤参数（不含分页）；列表、导出与标签纸共用
+func adminItemsFilter(c *gin.Context) (db.AdminItemsQuery, error) {
 	q := db.AdminItemsQuery{
-		Q:      c.Query("q"),
-		Status: c.Query("status"), // "", "open", "available", "overdue", "inactive"
+		Q:          c.Query("q"),
+		Status:     c.Query("status"),     // "", "open", "available", "overdue", "inactive"
+		ItemStatus: c.Query("itemStatus"), // "", "active", "maintenance", "retired", "lost", "stolen", "destroyed"
+		CategoryID: c.Query("categoryId"), // 含子分类
+		Tag:        c.Query("tag"),
+		Archived:   c.Query("archived"),   // "", "only", "all"
+		LocationID: c.Query("locationId"), // 当前位置，含下级
+	}
+	err := bindAttrFilters(c, &q)
+	return q, err
+}
+
+func (ic *ItemController) ListItemsAdmin(c *gin.Context) {
+	q, err := adminItemsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
 	}
 	if v := c.DefaultQuery("page", "1"); v != "" {
 		q.Page, _ = strconv.Atoi(v)
@@ -157,6 +258,9 @@ func (ic *ItemController) ListItemsAdmin(c *gin.Context) {
 	}
 
 	res, err := ic.Repo.ListItemsWithCurrentLoan(c.Request.Context(), q)
+	if err == nil {
+		err = ic.attachHolds(c, res.Items)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
 		return
@@ -169,6 +273,8 @@ type AdminBorrowReq struct {
 	UserName string     `json:"userName" binding:"required"`
 	DueAt    *time.Time `json:"dueAt,omitempty"`
 	Note     string     `json:"note,omitempty"`
+
+	Condition *db.ConditionInput `json:"condition,omitempty"` // 借出时的状况
 }
 
 func (ic *ItemController) AdminBorrow(c *gin.Context) {
@@ -186,13 +292,24 @@ func (ic *ItemController) AdminBorrow(c *gin.Context) {
 		return
 	}
 
+	adminID, _ := c.Get("userID")
+	actorID, _ := adminID.(string)
 	row, err := ic.Repo.CreateAdminLoan(c.Request.Context(), db.CreateAdminLoanInput{
-		ItemID: req.ToolID,
-		UserID: user.ID,
-		DueAt:  req.DueAt,
-		Note:   req.Note,
+		ItemID:    req.ToolID,
+		UserID:    user.ID,
+		DueAt:     req.DueAt,
+		Note:      req.Note,
+		Condition: req.Condition,
+		ActorID:   actorID,
 	})
 	if err != nil {
+		if errors.Is(err, db.ErrReservationConflict) || errors.Is(err, db.ErrClaimedByOther) || errors.Is(err, db.ErrMaintenanceDue) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if writeHeld(c, err) || writePolicyViolation(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -201,9 +318,12 @@ func (ic *ItemController) AdminBorrow(c *gin.Context) {
 }
 
 type AdminReturnReq struct {
-	ToolID   string `json:"toolId" binding:"required"`
-	Username string `json:"username" binding:"required"`
-	Note     string `json:"note,omitempty"`
+	ToolID     string  `json:"toolId" binding:"required"`
+	Username   string  `json:"username" binding:"required"`
+	Note       string  `json:"note,omitempty"`
+	LocationID *string `json:"locationId,omitempty"` // 放回的位置，不填则回到归位处
+
+	Condition *db.ConditionInput `json:"condition,omitempty"` // 归还时的状况
 }
 
 func (ic *ItemController) AdminReturn(c *gin.Context) {
@@ -221,10 +341,15 @@ func (ic *ItemController) AdminReturn(c *gin.Context) {
 	}
 
 	// 2) 执行归还
+	adminID, _ := c.Get("userID")
+	actorID, _ := adminID.(string)
 	row, err := ic.Repo.ReturnAdminLoan(c.Request.Context(), db.ReturnAdminLoanInput{
 		ItemID:           req.ToolID,
 		ReturnedByUserID: user.ID,
 		Note:             req.Note,
+		LocationID:       req.LocationID,
+		Condition:        req.Condition,
+		ActorID:          actorID,
 	})
 	if err != nil {
 		// 典型错误：no open loan for this item
@@ -234,3 +359,13 @@ func (ic *ItemController) AdminReturn(c *gin.Context) {
 
 	c.JSON(http.StatusOK, row)
 }
+
+// 管理员：查看已发送的到期/逾期提醒（?loanId= 可选）
+func (ic *ItemController) ListLoanNotifications(c *gin.Context) {
+	ns, err := ic.Repo.ListLoanNotifications(c.Request.Context(), c.Query("loanId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": ns})
+}
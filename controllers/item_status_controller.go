@@ -0,0 +1,58 @@
+// controllers/item_status_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// POST /api/admin/items/:id/status   {status, reason}
+func (ic *ItemController) ChangeStatus(c *gin.Context) {
+	var in struct {
+		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	v, _ := c.Get("userID")
+	adminID, _ := v.(string)
+
+	it, err := ic.Repo.ChangeItemStatus(c.Request.Context(), db.ChangeItemStatusInput{
+		ItemID:  c.Param("id"),
+		To:      in.Status,
+		Reason:  in.Reason,
+		ActorID: adminID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, app.H{"error": "item not found"})
+		case errors.Is(err, db.ErrUnknownItemStatus):
+			c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		case errors.Is(err, db.ErrInvalidTransition), errors.Is(err, db.ErrItemHasOpenLoan):
+			c.JSON(http.StatusConflict, app.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, it)
+}
+
+// GET /api/admin/items/:id/status-history
+func (ic *ItemController) StatusHistory(c *gin.Context) {
+	rows, err := ic.Repo.ListItemStatusHistory(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
@@ -0,0 +1,227 @@
+// controllers/kit_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type KitController struct{ *Srv }
+
+func NewKitController(s *Srv) *KitController { return &KitController{Srv: s} }
+
+func kitErrStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrInvalidKit), errors.Is(err, db.ErrInvalidLocation):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrKitExists), errors.Is(err, db.ErrKitReturned):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeBatchBorrowError 一次借出多件时指出是哪一件不可借（套装与购物车共用）
+func writeBatchBorrowError(c *gin.Context, err error) {
+	var me *db.BatchItemError
+	if !errors.As(err, &me) {
+		c.JSON(kitErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	body := app.H{"error": me.Error(), "itemId": me.ItemID, "serial": me.Serial}
+	var pv *db.PolicyViolation
+	var he *db.HeldError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, body)
+	case errors.As(err, &pv):
+		body["violation"] = pv
+		c.JSON(http.StatusUnprocessableEntity, body)
+	case errors.Is(err, db.ErrApprovalRequired):
+		body["approvalRequired"] = true
+		c.JSON(http.StatusConflict, body)
+	case errors.As(err, &he):
+		body["heldUntil"] = he.Hold.ExpiresAt
+		c.JSON(http.StatusConflict, body)
+	case errors.Is(err, db.ErrAlreadyBorrowed), errors.Is(err, db.ErrClaimedByOther),
+		errors.Is(err, db.ErrReservationConflict), errors.Is(err, db.ErrMaintenanceDue),
+		errors.Is(err, db.ErrItemNotAvailable):
+		c.JSON(http.StatusConflict, body)
+	default:
+		c.JSON(http.StatusInternalServerError, body)
+	}
+}
+
+type kitReq struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	ItemIDs     []string `json:"itemIds" binding:"required"`
+}
+
+// GET /api/kits   全部套装及成员、是否可整套借出
+func (kc *KitController) List(c *gin.Context) {
+	rows, err := kc.Repo.ListKits(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
+
+// GET /api/kits/:id
+func (kc *KitController) Get(c *gin.Context) {
+	row, err := kc.Repo.FindKit(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(kitErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, row)
+}
+
+// POST /api/admin/kits   {name, description?, itemIds}
+func (kc *KitController) Create(c *gin.Context) {
+	var req kitReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	k := &models.Kit{Name: req.Name, Description: req.Description}
+	if err := kc.Repo.CreateKit(c.Request.Context(), k, req.ItemIDs); err != nil {
+		c.JSON(kitErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	row, err := kc.Repo.FindKit(c.Request.Context(), k.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, row)
+}
+
+// PUT /api/admin/kits/:id   {name, description?, itemIds}  成员整体替换
+func (kc *KitController) Update(c *gin.Context) {
+	var req kitReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	k, err := kc.Repo.UpdateKit(c.Request.Context(), c.Param("id"), req.Name, req.Description, req.ItemIDs)
+	if err != nil {
+		c.JSON(kitErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	row, err := kc.Repo.FindKit(c.Request.Context(), k.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, row)
+}
+
+// DELETE /api/admin/kits/:id
+func (kc *KitController) Delete(c *gin.Context) {
+	if err := kc.Repo.DeleteKit(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(kitErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
+
+// POST /api/kits/:id/borrow   {dueAt?, note?}  全部成员一起借出，任一件不可借则都不借
+func (kc *KitController) Borrow(c *gin.Context) {
+	var in struct {
+		DueAt *time.Time `json:"dueAt"`
+		Note  string     `json:"note"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+			return
+		}
+	}
+	v, _ := c.Get("userID")
+	userID, _ := v.(string)
+	kit, err := kc.Repo.FindKit(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(kitErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	ids := make([]string, len(kit.Items))
+	for i, m := range kit.Items {
+		ids[i] = m.ItemID
+	}
+	row, err := kc.Repo.BorrowKit(c.Request.Context(), db.BorrowKitInput{
+		KitID:  c.Param("id"),
+		UserID: userID,
+		DueAt:  in.DueAt,
+		Note:   in.Note,
+	})
+	if err != nil {
+		writeBatchBorrowError(c, err)
+		return
+	}
+	_ = kc.Cart.Remove(c.Request.Context(), userID, ids...)
+	c.JSON(http.StatusCreated, row)
+}
+
+// POST /api/kits/checkouts/:checkoutId/return   {locationId?}  借用人本人或管理员
+func (kc *KitController) Return(c *gin.Context) {
+	var in struct {
+		LocationID *string `json:"locationId"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+			return
+		}
+	}
+	v, _ := c.Get("userID")
+	userID, _ := v.(string)
+	co, err := kc.Repo.FindKitCheckout(c.Request.Context(), c.Param("checkoutId"))
+	if err != nil {
+		c.JSON(kitErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	if co.UserID != userID && !c.GetBool("isAdmin") {
+		c.JSON(http.StatusForbidden, app.H{"error": "not your checkout"})
+		return
+	}
+	row, err := kc.Repo.ReturnKit(c.Request.Context(), co.ID, userID, in.LocationID)
+	if err != nil {
+		c.JSON(kitErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, row)
+}
+
+// GET /api/kits/checkouts?open=true   我的整套借出
+func (kc *KitController) ListMine(c *gin.Context) {
+	v, _ := c.Get("userID")
+	userID, _ := v.(string)
+	rows, err := kc.Repo.ListKitCheckouts(c.Request.Context(), userID, c.Query("open") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
+
+// GET /api/admin/kit-checkouts?userId=&open=true
+func (kc *KitController) ListAdmin(c *gin.Context) {
+	rows, err := kc.Repo.ListKitCheckouts(c.Request.Context(), c.Query("userId"), c.Query("open") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
@@ -0,0 +1,74 @@
+// controllers/loan_loss_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// POST /api/admin/loans/:loanId/declare   {outcome: lost|stolen|destroyed, note?}  关闭回不来的借用
+func (ic *ItemController) DeclareLoss(c *gin.Context) {
+	var in struct {
+		Outcome string `json:"outcome" binding:"required"`
+		Note    string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	v, _ := c.Get("userID")
+	adminID, _ := v.(string)
+
+	loss, err := ic.Repo.DeclareLoanLoss(c.Request.Context(), db.DeclareLossInput{
+		LoanID:  c.Param("loanId"),
+		Outcome: in.Outcome,
+		Note:    in.Note,
+		ActorID: adminID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, app.H{"error": "loan not found"})
+		case errors.Is(err, db.ErrInvalidLossOutcome):
+			c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		case errors.Is(err, db.ErrLoanClosed):
+			c.JSON(http.StatusConflict, app.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, loss)
+}
+
+// GET /api/admin/loan-losses?userId=&itemId=&outcome=
+func (ic *ItemController) ListLossesAdmin(c *gin.Context) {
+	rows, err := ic.Repo.ListLoanLosses(c.Request.Context(), db.LoanLossesQuery{
+		UserID:  c.Query("userId"),
+		ItemID:  c.Query("itemId"),
+		Outcome: c.Query("outcome"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
+
+// GET /api/items/loans/losses   我负责的丢失 / 被盗 / 损毁记录
+func (ic *ItemController) ListMyLosses(c *gin.Context) {
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	rows, err := ic.Repo.ListLoanLosses(c.Request.Context(), db.LoanLossesQuery{UserID: uid})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
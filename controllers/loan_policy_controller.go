@@ -0,0 +1,174 @@
+// controllers/loan_policy_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PolicyController struct{ *Srv }
+
+func NewPolicyController(s *Srv) *PolicyController { return &PolicyController{Srv: s} }
+
+// 借出被策略拒绝时统一返回 422 + 具体规则；返回 true 表示已写响应
+func writePolicyViolation(c *gin.Context, err error) bool {
+	var pv *db.PolicyViolation
+	if !errors.As(err, &pv) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, app.H{"error": pv.Error(), "violation": pv})
+	return true
+}
+
+type policyReq struct {
+	MaxOpenLoans     *int    `json:"maxOpenLoans"`
+	DefaultLoanHours *int    `json:"defaultLoanHours"`
+	MaxLoanHours     *int    `json:"maxLoanHours"`
+	RequiresApproval *bool   `json:"requiresApproval"`
+	RateCents        *int64  `json:"rateCents"`
+	LateFeeCents     *int64  `json:"lateFeeCents"`
+	BillingUnit      *string `json:"billingUnit"`
+	BorrowFrom       *string `json:"borrowFrom"`
+	BorrowUntil      *string `json:"borrowUntil"`
+	Timezone         string  `json:"timezone"`
+}
+
+func (pc *PolicyController) upsert(c *gin.Context, scope, scopeID string) {
+	var req policyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	v, _ := c.Get("userID")
+	adminID, _ := v.(string)
+
+	p := &models.LoanPolicy{
+		Scope:            scope,
+		ScopeID:          scopeID,
+		MaxOpenLoans:     req.MaxOpenLoans,
+		DefaultLoanHours: req.DefaultLoanHours,
+		MaxLoanHours:     req.MaxLoanHours,
+		RequiresApproval: req.RequiresApproval,
+		RateCents:        req.RateCents,
+		LateFeeCents:     req.LateFeeCents,
+		BillingUnit:      req.BillingUnit,
+		BorrowFrom:       req.BorrowFrom,
+		BorrowUntil:      req.BorrowUntil,
+		Timezone:         req.Timezone,
+		UpdatedBy:        adminID,
+	}
+	if err := pc.Repo.UpsertLoanPolicy(c.Request.Context(), p); err != nil {
+		switch {
+		case errors.Is(err, db.ErrInvalidPolicy):
+			c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, app.H{"error": scope + " not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// GET /api/admin/policies
+func (pc *PolicyController) List(c *gin.Context) {
+	res, err := pc.Repo.GetLoanPolicies(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// PUT /api/admin/policies/global
+func (pc *PolicyController) PutGlobal(c *gin.Context) {
+	pc.upsert(c, models.PolicyScopeGlobal, "")
+}
+
+// PUT /api/admin/policies/items/:id
+func (pc *PolicyController) PutItem(c *gin.Context) {
+	pc.upsert(c, models.PolicyScopeItem, c.Param("id"))
+}
+
+// DELETE /api/admin/policies/items/:id   恢复为分类/全局策略
+func (pc *PolicyController) DeleteItem(c *gin.Context) {
+	if err := pc.Repo.DeleteLoanPolicy(c.Request.Context(), models.PolicyScopeItem, c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
+
+// PUT /api/admin/policies/categories/:id   对该分类及其子分类下的物品生效
+func (pc *PolicyController) PutCategory(c *gin.Context) {
+	pc.upsert(c, models.PolicyScopeCategory, c.Param("id"))
+}
+
+// DELETE /api/admin/policies/categories/:id
+func (pc *PolicyController) DeleteCategory(c *gin.Context) {
+	if err := pc.Repo.DeleteLoanPolicy(c.Request.Context(), models.PolicyScopeCategory, c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
+
+// POST /api/admin/policies/blackouts
+func (pc *PolicyController) CreateBlackout(c *gin.Context) {
+	var in struct {
+		StartsAt time.Time `json:"startsAt" binding:"required"`
+		EndsAt   time.Time `json:"endsAt" binding:"required"`
+		Reason   string    `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	v, _ := c.Get("userID")
+	adminID, _ := v.(string)
+
+	b := &models.LoanBlackout{StartsAt: in.StartsAt, EndsAt: in.EndsAt, Reason: in.Reason, CreatedBy: adminID}
+	if err := pc.Repo.CreateLoanBlackout(c.Request.Context(), b); err != nil {
+		if errors.Is(err, db.ErrInvalidPolicy) {
+			c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, b)
+}
+
+// DELETE /api/admin/policies/blackouts/:id
+func (pc *PolicyController) DeleteBlackout(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": "invalid id"})
+		return
+	}
+	if err := pc.Repo.DeleteLoanBlackout(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
+
+// GET /api/items/:id/policy   当前对该物品生效的策略
+func (pc *PolicyController) Effective(c *gin.Context) {
+	eff, err := pc.Repo.EffectiveLoanPolicy(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, eff)
+}
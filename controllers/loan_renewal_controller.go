@@ -0,0 +1,114 @@
+// controllers/loan_renewal_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func renewalErrStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrNotLoanOwner):
+		return http.StatusForbidden
+	case errors.Is(err, db.ErrInvalidRenewalDate):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrLoanClosed),
+		errors.Is(err, db.ErrRenewalLimit),
+		errors.Is(err, db.ErrRenewalTooLong),
+		errors.Is(err, db.ErrRenewalWaitlisted),
+		errors.Is(err, db.ErrReservationConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+type renewReq struct {
+	DueAt  *time.Time `json:"dueAt,omitempty"` // 不传则按默认周期顺延
+	Reason string     `json:"reason,omitempty"`
+}
+
+// POST /api/items/loans/:loanId/renew   借用人自己续借
+func (ic *ItemController) Renew(c *gin.Context) {
+	v, ok := c.Get("userID")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, app.H{"error": "unauthorized"})
+		return
+	}
+	userID, _ := v.(string)
+
+	var req renewReq
+	_ = c.ShouldBindJSON(&req)
+
+	loan, err := ic.Repo.RenewLoan(c.Request.Context(), db.RenewLoanInput{
+		LoanID:   c.Param("loanId"),
+		ActorID:  userID,
+		NewDueAt: req.DueAt,
+		Reason:   req.Reason,
+	})
+	if err != nil {
+		if writePolicyViolation(c, err) {
+			return
+		}
+		c.JSON(renewalErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, loan)
+}
+
+// POST /api/admin/loans/:loanId/extend   管理员延期任意借用
+func (ic *ItemController) AdminExtend(c *gin.Context) {
+	v, _ := c.Get("userID")
+	adminID, _ := v.(string)
+
+	var req renewReq
+	_ = c.ShouldBindJSON(&req)
+
+	loan, err := ic.Repo.RenewLoan(c.Request.Context(), db.RenewLoanInput{
+		LoanID:   c.Param("loanId"),
+		ActorID:  adminID,
+		NewDueAt: req.DueAt,
+		Reason:   req.Reason,
+		ByAdmin:  true,
+	})
+	if err != nil {
+		if writePolicyViolation(c, err) {
+			return
+		}
+		c.JSON(renewalErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, loan)
+}
+
+// GET /api/items/loans/:loanId/extensions   借用人或管理员查看延期历史
+func (ic *ItemController) ListLoanExtensions(c *gin.Context) {
+	v, _ := c.Get("userID")
+	userID, _ := v.(string)
+
+	loan, err := ic.Repo.FindLoanByID(c.Request.Context(), c.Param("loanId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, app.H{"error": "loan not found"})
+		return
+	}
+	if loan.UserID != userID && !c.GetBool("isAdmin") {
+		c.JSON(http.StatusForbidden, app.H{"error": db.ErrNotLoanOwner.Error()})
+		return
+	}
+
+	xs, err := ic.Repo.ListLoanExtensions(c.Request.Context(), loan.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"loan": loan, "items": xs})
+}
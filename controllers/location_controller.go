@@ -0,0 +1,127 @@
+// controllers/location_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LocationController struct{ *Srv }
+
+func NewLocationController(s *Srv) *LocationController { return &LocationController{Srv: s} }
+
+func locationErrStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrInvalidLocation):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrLocationExists), errors.Is(err, db.ErrLocationCycle), errors.Is(err, db.ErrLocationNotEmpty):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+type locationReq struct {
+	Name     string  `json:"name" binding:"required"`
+	Kind     string  `json:"kind"` // 仅新建时使用：site/room/shelf/bin
+	Note     string  `json:"note"`
+	ParentID *string `json:"parentId"`
+}
+
+// GET /api/locations   扁平列表，带路径和当前物品数
+func (lc *LocationController) List(c *gin.Context) {
+	rows, err := lc.Repo.ListLocations(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
+
+// POST /api/admin/locations   {name, kind, parentId?, note?}
+func (lc *LocationController) Create(c *gin.Context) {
+	var req locationReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	l := &models.Location{Name: req.Name, Kind: req.Kind, Note: req.Note, ParentID: req.ParentID}
+	if err := lc.Repo.CreateLocation(c.Request.Context(), l); err != nil {
+		c.JSON(locationErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, l)
+}
+
+// PUT /api/admin/locations/:id   {name, parentId?, note?}  改名 / 移动
+func (lc *LocationController) Update(c *gin.Context) {
+	var req locationReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	l, err := lc.Repo.UpdateLocation(c.Request.Context(), c.Param("id"), req.Name, req.Note, req.ParentID)
+	if err != nil {
+		c.JSON(locationErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, l)
+}
+
+// DELETE /api/admin/locations/:id   只能删空位置
+func (lc *LocationController) Delete(c *gin.Context) {
+	if err := lc.Repo.DeleteLocation(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(locationErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
+
+// GET /api/admin/locations/:id/items?scope=current|home&page=&size=   含下级位置
+func (lc *LocationController) Items(c *gin.Context) {
+	q := db.AdminItemsQuery{
+		LocationID: c.Param("id"),
+		AtHome:     c.Query("scope") == "home",
+		Archived:   c.Query("archived"),
+	}
+	q.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	q.Size, _ = strconv.Atoi(c.DefaultQuery("size", "50"))
+	res, err := lc.Repo.ListItemsWithCurrentLoan(c.Request.Context(), q)
+	if err == nil {
+		err = lc.attachHolds(c, res.Items)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true, "items": res})
+}
+
+// PUT /api/admin/items/:id/location   {homeLocationId, currentLocationId}  整体替换，null 表示清空
+func (lc *LocationController) SetItemLocation(c *gin.Context) {
+	var in struct {
+		HomeLocationID    *string `json:"homeLocationId"`
+		CurrentLocationID *string `json:"currentLocationId"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	it, err := lc.Repo.SetItemLocation(c.Request.Context(), c.Param("id"), in.HomeLocationID, in.CurrentLocationID)
+	if err != nil {
+		c.JSON(locationErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", itemETag(it.Version))
+	c.JSON(http.StatusOK, it)
+}
@@ -0,0 +1,220 @@
+// controllers/maintenance_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type MaintenanceController struct{ *Srv }
+
+func NewMaintenanceController(s *Srv) *MaintenanceController {
+	return &MaintenanceController{Srv: s}
+}
+
+func maintenanceErrStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrInvalidPlan):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrNotLoanOwner):
+		return http.StatusForbidden
+	case errors.Is(err, db.ErrInvalidWorkOrderMove), errors.Is(err, db.ErrLoanClosed):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+type planReq struct {
+	Kind               string     `json:"kind" binding:"required"` // calibration/service
+	Title              string     `json:"title" binding:"required"`
+	IntervalDays       *int       `json:"intervalDays"`
+	IntervalUsageHours *int       `json:"intervalUsageHours"`
+	BlocksBorrowing    bool       `json:"blocksBorrowing"` // calibration 始终为 true
+	LastDoneAt         *time.Time `json:"lastDoneAt"`
+}
+
+func (r planReq) toModel() *models.MaintenancePlan {
+	return &models.MaintenancePlan{
+		Kind:               r.Kind,
+		Title:              r.Title,
+		IntervalDays:       r.IntervalDays,
+		IntervalUsageHours: r.IntervalUsageHours,
+		BlocksBorrowing:    r.BlocksBorrowing,
+		LastDoneAt:         r.LastDoneAt,
+	}
+}
+
+// POST /api/admin/items/:id/maintenance-plans
+func (mc *MaintenanceController) CreatePlan(c *gin.Context) {
+	var req planReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	p := req.toModel()
+	p.ItemID = c.Param("id")
+	if err := mc.Repo.CreateMaintenancePlan(c.Request.Context(), p); err != nil {
+		c.JSON(maintenanceErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// GET /api/admin/items/:id/maintenance-plans
+func (mc *MaintenanceController) ListPlans(c *gin.Context) {
+	rows, err := mc.Repo.ListMaintenancePlans(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
+
+// PUT /api/admin/maintenance-plans/:planId
+func (mc *MaintenanceController) UpdatePlan(c *gin.Context) {
+	var req planReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	p := req.toModel()
+	p.ID = c.Param("planId")
+	out, err := mc.Repo.UpdateMaintenancePlan(c.Request.Context(), p)
+	if err != nil {
+		c.JSON(maintenanceErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// DELETE /api/admin/maintenance-plans/:planId   停用
+func (mc *MaintenanceController) DeletePlan(c *gin.Context) {
+	if err := mc.Repo.DeactivateMaintenancePlan(c.Request.Context(), c.Param("planId")); err != nil {
+		c.JSON(maintenanceErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
+
+// GET /api/admin/maintenance/upcoming?days=30
+func (mc *MaintenanceController) Upcoming(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if days <= 0 {
+		days = 30
+	}
+	rows, err := mc.Repo.ListUpcomingMaintenance(c.Request.Context(), time.Duration(days)*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
+
+// POST /api/admin/work-orders   {itemId, planId?, title, description}
+func (mc *MaintenanceController) CreateWorkOrder(c *gin.Context) {
+	var in struct {
+		ItemID      string  `json:"itemId" binding:"required"`
+		PlanID      *string `json:"planId"`
+		Title       string  `json:"title"`
+		Description string  `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	if in.PlanID == nil && in.Title == "" {
+		c.JSON(http.StatusBadRequest, app.H{"error": "title is required"})
+		return
+	}
+	v, _ := c.Get("userID")
+	adminID, _ := v.(string)
+
+	wo := &models.WorkOrder{
+		ItemID:      in.ItemID,
+		PlanID:      in.PlanID,
+		Source:      models.WorkOrderManual,
+		Title:       in.Title,
+		Description: in.Description,
+		ReportedBy:  adminID,
+	}
+	if in.PlanID != nil {
+		wo.Source = models.WorkOrderScheduled
+	}
+	if err := mc.Repo.CreateWorkOrder(c.Request.Context(), wo); err != nil {
+		c.JSON(maintenanceErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, wo)
+}
+
+// GET /api/admin/work-orders?itemId=&status=&source=
+func (mc *MaintenanceController) ListWorkOrders(c *gin.Context) {
+	rows, err := mc.Repo.ListWorkOrders(c.Request.Context(), db.WorkOrdersQuery{
+		ItemID: c.Query("itemId"),
+		Status: c.Query("status"),
+		Source: c.Query("source"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
+
+// POST /api/admin/work-orders/:id/status   {status, resolution}
+func (mc *MaintenanceController) UpdateWorkOrderStatus(c *gin.Context) {
+	var in struct {
+		Status     string `json:"status" binding:"required"`
+		Resolution string `json:"resolution"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	v, _ := c.Get("userID")
+	adminID, _ := v.(string)
+
+	wo, err := mc.Repo.UpdateWorkOrderStatus(c.Request.Context(), c.Param("id"), in.Status, in.Resolution, adminID)
+	if err != nil {
+		c.JSON(maintenanceErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, wo)
+}
+
+// POST /api/items/loans/:loanId/fault   借用人报修手上的物品
+func (mc *MaintenanceController) ReportFault(c *gin.Context) {
+	var in struct {
+		Title       string `json:"title"`
+		Description string `json:"description" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	v, ok := c.Get("userID")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, app.H{"error": "unauthorized"})
+		return
+	}
+	userID, _ := v.(string)
+
+	wo, err := mc.Repo.ReportFault(c.Request.Context(), c.Param("loanId"), userID, in.Title, in.Description)
+	if err != nil {
+		c.JSON(maintenanceErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, wo)
+}
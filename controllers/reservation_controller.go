@@ -0,0 +1,174 @@
+// controllers/reservation_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ReservationController struct{ *Srv }
+
+func NewReservationController(s *Srv) *ReservationController {
+	return &ReservationController{Srv: s}
+}
+
+// 解析可选的 RFC3339 时间查询参数
+func parseTimeQuery(c *gin.Context, key string) (*time.Time, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func reservationErrStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrReservationConflict):
+		return http.StatusConflict
+	case errors.Is(err, db.ErrInvalidReservation):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrNotReservationOwner):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// POST /api/items/:id/reservations
+func (rc *ReservationController) Create(c *gin.Context) {
+	itemID := c.Param("id")
+	v, ok := c.Get("userID")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, app.H{"error": "unauthorized"})
+		return
+	}
+	userID, _ := v.(string)
+
+	var in struct {
+		StartAt time.Time `json:"startAt" binding:"required"`
+		EndAt   time.Time `json:"endAt" binding:"required"`
+		Note    string    `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+
+	rv, err := rc.Repo.CreateReservation(c.Request.Context(), db.CreateReservationInput{
+		ItemID:  itemID,
+		UserID:  userID,
+		StartAt: in.StartAt,
+		EndAt:   in.EndAt,
+		Note:    in.Note,
+	})
+	if err != nil {
+		c.JSON(reservationErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, rv)
+}
+
+// GET /api/items/:id/availability?from=&to=   默认未来 14 天
+func (rc *ReservationController) Availability(c *gin.Context) {
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": "invalid from"})
+		return
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": "invalid to"})
+		return
+	}
+	if from == nil {
+		now := time.Now().UTC()
+		from = &now
+	}
+	if to == nil {
+		t := from.Add(14 * 24 * time.Hour)
+		to = &t
+	}
+	if !to.After(*from) {
+		c.JSON(http.StatusBadRequest, app.H{"error": "to must be after from"})
+		return
+	}
+
+	res, err := rc.Repo.GetItemAvailability(c.Request.Context(), c.Param("id"), *from, *to)
+	if err != nil {
+		c.JSON(reservationErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GET /api/reservations?itemId=&status=&from=&to=   只看自己的
+func (rc *ReservationController) ListMine(c *gin.Context) {
+	v, ok := c.Get("userID")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, app.H{"error": "unauthorized"})
+		return
+	}
+	userID, _ := v.(string)
+	rc.list(c, userID)
+}
+
+// GET /api/admin/reservations?userId=&itemId=&status=&from=&to=
+func (rc *ReservationController) ListAdmin(c *gin.Context) {
+	rc.list(c, c.Query("userId"))
+}
+
+func (rc *ReservationController) list(c *gin.Context, userID string) {
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": "invalid from"})
+		return
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": "invalid to"})
+		return
+	}
+	rs, err := rc.Repo.ListReservations(c.Request.Context(), db.ReservationsQuery{
+		UserID: userID,
+		ItemID: c.Query("itemId"),
+		Status: c.Query("status"),
+		From:   from,
+		To:     to,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rs})
+}
+
+// DELETE /api/reservations/:id   本人或管理员可取消
+func (rc *ReservationController) Cancel(c *gin.Context) {
+	v, ok := c.Get("userID")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, app.H{"error": "unauthorized"})
+		return
+	}
+	userID, _ := v.(string)
+	isAdmin := c.GetBool("isAdmin")
+
+	rv, err := rc.Repo.CancelReservation(c.Request.Context(), c.Param("id"), userID, isAdmin)
+	if err != nil {
+		c.JSON(reservationErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rv)
+}
@@ -0,0 +1,183 @@
+// controllers/scan_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ScanController struct{ *Srv }
+
+func NewScanController(s *Srv) *ScanController { return &ScanController{Srv: s} }
+
+// 扫码后建议的动作
+const (
+	ScanActionBorrow      = "borrow"
+	ScanActionReturn      = "return"
+	ScanActionUnavailable = "unavailable" // 被他人借走或物品不可借
+)
+
+func identErrStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrUnknownIdentifier), errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrInvalidIdentifier):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrIdentifierTaken):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// scanAction 按当前借用判断扫码含义：自己借着（或管理员代还）→ 还；空闲且可借 → 借
+func scanAction(it *models.Item, open *models.Loan, userID string, isAdmin bool) string {
+	if open != nil {
+		if open.UserID == userID || isAdmin {
+			return ScanActionReturn
+		}
+		return ScanActionUnavailable
+	}
+	if it.Status != models.ItemStatusActive || it.ArchivedAt != nil || it.InUse {
+		return ScanActionUnavailable
+	}
+	return ScanActionBorrow
+}
+
+func (sc *ScanController) resolve(c *gin.Context, code string) (*models.Item, *models.Loan, bool) {
+	it, err := sc.Repo.ResolveItem(c.Request.Context(), code)
+	if err != nil {
+		c.JSON(identErrStatus(err), app.H{"error": err.Error()})
+		return nil, nil, false
+	}
+	open, err := sc.Repo.FindOpenLoanByItem(c.Request.Context(), it.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return nil, nil, false
+	}
+	return it, open, true
+}
+
+// GET /api/scan/resolve?code=   只解析不动作，给前端展示“将要借/还”
+func (sc *ScanController) Resolve(c *gin.Context) {
+	it, open, ok := sc.resolve(c, c.Query("code"))
+	if !ok {
+		return
+	}
+	v, _ := c.Get("userID")
+	uid, _ := v.(string)
+	out := app.H{"item": it, "action": scanAction(it, open, uid, c.GetBool("isAdmin"))}
+	// 借用人信息只给本人和管理员
+	if open != nil && (open.UserID == uid || c.GetBool("isAdmin")) {
+		out["openLoan"] = open
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// POST /api/scan   {code, dueAt?, note?, locationId?, condition?}   一次扫码：空闲则借出，本人在借则归还
+func (sc *ScanController) Toggle(c *gin.Context) {
+	var in struct {
+		Code       string     `json:"code" binding:"required"`
+		DueAt      *time.Time `json:"dueAt"`
+		Note       string     `json:"note"`
+		LocationID *string    `json:"locationId"` // 归还时放回的位置
+
+		Condition *db.ConditionInput `json:"condition"` // 借出 / 归还时的状况
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	v, ok := c.Get("userID")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, app.H{"error": "unauthorized"})
+		return
+	}
+	userID, _ := v.(string)
+
+	it, open, ok := sc.resolve(c, in.Code)
+	if !ok {
+		return
+	}
+	switch scanAction(it, open, userID, c.GetBool("isAdmin")) {
+	case ScanActionReturn:
+		loan, err := sc.Repo.ReturnLoan(c.Request.Context(), open.ID, userID, in.LocationID, in.Condition)
+		if err != nil {
+			if errors.Is(err, db.ErrInvalidLocation) || errors.Is(err, db.ErrInvalidCondition) {
+				c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, app.H{"action": ScanActionReturn, "item": it, "loan": loan})
+	case ScanActionBorrow:
+		loan, err := sc.Repo.BorrowItem(c.Request.Context(), userID, it.ID, in.DueAt, in.Note, in.Condition)
+		if errors.Is(err, db.ErrApprovalRequired) {
+			if req, ok := sc.submitBorrowRequest(c, userID, it.ID, in.DueAt, in.Note); ok {
+				c.JSON(http.StatusAccepted, app.H{"action": ScanActionBorrow, "item": it, "approvalRequired": true, "request": req})
+			}
+			return
+		}
+		if err != nil {
+			writeBorrowError(c, err)
+			return
+		}
+		_ = sc.Cart.Remove(c.Request.Context(), userID, it.ID)
+		c.JSON(http.StatusCreated, app.H{"action": ScanActionBorrow, "item": it, "loan": loan})
+	default:
+		if open != nil {
+			c.JSON(http.StatusConflict, app.H{"error": "already borrowed", "item": it, "canJoinWaitlist": true})
+			return
+		}
+		c.JSON(http.StatusConflict, app.H{"error": "item is not available", "item": it})
+	}
+}
+
+// POST /api/admin/items/:id/identifiers   {kind: barcode|nfc|alias, value}
+func (sc *ScanController) AddIdentifier(c *gin.Context) {
+	var in struct {
+		Kind  string `json:"kind" binding:"required"`
+		Value string `json:"value" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, app.H{"error": err.Error()})
+		return
+	}
+	v, _ := c.Get("userID")
+	adminID, _ := v.(string)
+
+	ident := &models.ItemIdentifier{ItemID: c.Param("id"), Kind: in.Kind, Value: in.Value, CreatedBy: adminID}
+	if err := sc.Repo.AddItemIdentifier(c.Request.Context(), ident); err != nil {
+		c.JSON(identErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, ident)
+}
+
+// GET /api/admin/items/:id/identifiers
+func (sc *ScanController) ListIdentifiers(c *gin.Context) {
+	rows, err := sc.Repo.ListItemIdentifiers(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
+
+// DELETE /api/admin/identifiers/:id
+func (sc *ScanController) DeleteIdentifier(c *gin.Context) {
+	if err := sc.Repo.DeleteItemIdentifier(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(identErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
@@ -0,0 +1,38 @@
+// controllers/search_controller.go
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchController struct{ *Srv }
+
+func NewSearchController(s *Srv) *SearchController { return &SearchController{Srv: s} }
+
+// GET /api/search/suggest?q=&limit=   边输入边联想；用户列表只对管理员返回
+func (sc *SearchController) Suggest(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "8"))
+	if limit <= 0 || limit > 20 {
+		limit = 8
+	}
+	q := c.Query("q")
+	items, err := sc.Repo.SuggestItems(c.Request.Context(), q, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	users := []db.UserSuggestion{}
+	if isAdmin, _ := c.Get("isAdmin"); isAdmin == true {
+		if users, err = sc.Repo.SuggestUsers(c.Request.Context(), q, limit); err != nil {
+			c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, app.H{"items": items, "users": users})
+}
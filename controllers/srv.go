@@ -8,9 +8,11 @@ import (
 	"time"
 
 	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/cart"
 	"Gin_postgres_redis_rent_tool/db"
 	"Gin_postgres_redis_rent_tool/models"
 	"Gin_postgres_redis_rent_tool/session"
+	"Gin_postgres_redis_rent_tool/storage"
 
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/google/uuid"
@@ -21,16 +23,23 @@ type Srv struct {
 	Repo      *db.Repo
 	Sess      *session.Store
 	AppSess   *session.AppSessionStore
+	Blobs     storage.BlobStore
+	Cart      *cart.Store
 	WebOrigin string
 	Cfg       app.Config
 }
 
 func GetSrv(a *app.App) *Srv {
+	carts := cart.NewStore(a.RDB, a.Config.CartHoldTTL)
+	repo := db.NewRepo(a.DB)
+	repo.Cart = carts
 	return &Srv{
 		WA:        a.WA,
-		Repo:      db.NewRepo(a.DB),
+		Repo:      repo,
 		Sess:      session.NewStore(a.RDB, a.Config.SessionTTL),
 		AppSess:   session.NewAppSessionStore(a.RDB, 24*time.Hour),
+		Blobs:     a.Blobs,
+		Cart:      carts,
 		WebOrigin: a.Config.WebOrigin,
 		Cfg:       a.Config,
 	}
@@ -0,0 +1,90 @@
+// controllers/waitlist_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WaitlistController struct{ *Srv }
+
+func NewWaitlistController(s *Srv) *WaitlistController { return &WaitlistController{Srv: s} }
+
+func waitlistErrStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, db.ErrNotOnWaitlist):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrAlreadyWaitlisted),
+		errors.Is(err, db.ErrWaitlistNotNeeded),
+		errors.Is(err, db.ErrBorrowerCannotWait):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// POST /api/items/:id/waitlist
+func (wc *WaitlistController) Join(c *gin.Context) {
+	v, ok := c.Get("userID")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, app.H{"error": "unauthorized"})
+		return
+	}
+	userID, _ := v.(string)
+
+	e, err := wc.Repo.JoinWaitlist(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		c.JSON(waitlistErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, e)
+}
+
+// DELETE /api/items/:id/waitlist
+func (wc *WaitlistController) Leave(c *gin.Context) {
+	v, ok := c.Get("userID")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, app.H{"error": "unauthorized"})
+		return
+	}
+	userID, _ := v.(string)
+
+	if err := wc.Repo.LeaveWaitlist(c.Request.Context(), c.Param("id"), userID); err != nil {
+		c.JSON(waitlistErrStatus(err), app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"ok": true})
+}
+
+// GET /api/items/waitlist   我在排的队（含位置、认领截止时间）
+func (wc *WaitlistController) ListMine(c *gin.Context) {
+	v, ok := c.Get("userID")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, app.H{"error": "unauthorized"})
+		return
+	}
+	userID, _ := v.(string)
+
+	rows, err := wc.Repo.ListMyWaitlist(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
+
+// GET /api/admin/items/:id/waitlist
+func (wc *WaitlistController) ListItem(c *gin.Context) {
+	rows, err := wc.Repo.ListItemWaitlist(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, app.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, app.H{"items": rows})
+}
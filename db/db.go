@@ -2,10 +2,12 @@ package db
 
 import (
 	"Gin_postgres_redis_rent_tool/models"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -38,7 +40,16 @@ func ConnectDB() *gorm.DB {
 }
 
 func Migrate(db *gorm.DB) error {
-	if err := db.AutoMigrate(&models.User{}, &models.Credential{}, &models.Invite{}, &models.Item{}, &models.Loan{}, &models.UnlockLog{}); err != nil {
+	if err := db.AutoMigrate(
+		&models.User{}, &models.Credential{}, &models.Invite{}, &models.Item{}, &models.Loan{}, &models.UnlockLog{},
+		&models.Reservation{}, &models.WaitlistEntry{}, &models.LoanExtension{},
+		&models.LoanPolicy{}, &models.LoanBlackout{}, &models.LoanNotification{}, &models.ItemStatusChange{},
+		&models.MaintenancePlan{}, &models.WorkOrder{}, &models.Attachment{}, &models.ItemIdentifier{},
+		&models.Category{}, &models.ItemTag{}, &models.CategoryAttribute{},
+		&models.Location{}, &models.Kit{}, &models.KitItem{}, &models.KitCheckout{},
+		&models.ChecklistItem{}, &models.ConditionReport{}, &models.LoanHandover{},
+		&models.BorrowRequest{}, &models.LoanLoss{}, &models.LedgerEntry{},
+	); err != nil {
 		return err
 	}
 
@@ -51,6 +62,115 @@ func Migrate(db *gorm.DB) error {
 		return err
 	}
 
+	// 同一物品的有效预约时间段不得重叠（排他约束需要 btree_gist 支持 uuid 的 =）
+	if err := db.Exec(`CREATE EXTENSION IF NOT EXISTS btree_gist;`).Error; err != nil {
+		return err
+	}
+	if err := db.Exec(fmt.Sprintf(`
+	  DO $$
+	  BEGIN
+	    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = '%s_no_overlap') THEN
+	      ALTER TABLE %s
+	        ADD CONSTRAINT %s_valid_range CHECK (end_at > start_at),
+	        ADD CONSTRAINT %s_no_overlap EXCLUDE USING gist (
+	          item_id WITH =,
+	          tstzrange(start_at, end_at, '[)') WITH &&
+	        ) WHERE (status = 'confirmed');
+	    END IF;
+	  END
+	  $$;
+	`, models.ReservationTable, models.ReservationTable, models.ReservationTable, models.ReservationTable)).Error; err != nil {
+		return err
+	}
+
+	// 排队：同一用户在同一物品上最多一条有效记录；同一物品同一时刻最多一个认领
+	if err := db.Exec(fmt.Sprintf(`
+	  CREATE UNIQUE INDEX IF NOT EXISTS %s_one_active_per_user
+	  ON %s (item_id, user_id)
+	  WHERE status IN ('waiting', 'offered');
+	`, models.WaitlistTable, models.WaitlistTable)).Error; err != nil {
+		return err
+	}
+	if err := db.Exec(fmt.Sprintf(`
+	  CREATE UNIQUE INDEX IF NOT EXISTS %s_one_offer_per_item
+	  ON %s (item_id)
+	  WHERE status = 'offered';
+	`, models.WaitlistTable, models.WaitlistTable)).Error; err != nil {
+		return err
+	}
+
+	// 同一借用最多一条待确认的转交
+	if err := db.Exec(fmt.Sprintf(`
+	  CREATE UNIQUE INDEX IF NOT EXISTS %s_one_pending_per_loan
+	  ON %s (loan_id)
+	  WHERE status = 'pending';
+	`, models.LoanHandoverTable, models.LoanHandoverTable)).Error; err != nil {
+		return err
+	}
+
+	// 补齐旧数据的借用结束方式（outcome 字段之前已关闭的借用）
+	if err := db.Exec(fmt.Sprintf(`
+	  UPDATE %s l SET outcome = CASE
+	    WHEN EXISTS (SELECT 1 FROM %s h WHERE h.loan_id = l.id AND h.status = 'accepted') THEN '%s'
+	    ELSE '%s' END
+	  WHERE l.returned_at IS NOT NULL AND (l.outcome IS NULL OR l.outcome = '');
+	`, models.LoanTable, models.LoanHandoverTable, models.LoanOutcomeHandedOver, models.LoanOutcomeReturned)).Error; err != nil {
+		return err
+	}
+
+	// 每条借用的租金、逾期费各最多一条
+	if err := db.Exec(fmt.Sprintf(`
+	  CREATE UNIQUE INDEX IF NOT EXISTS %s_one_charge_per_loan
+	  ON %s (loan_id, kind)
+	  WHERE kind IN ('%s', '%s');
+	`, models.LedgerEntryTable, models.LedgerEntryTable, models.LedgerRental, models.LedgerLateFee)).Error; err != nil {
+		return err
+	}
+
+	// 同一用户对同一物品最多一条待审批的借用申请
+	if err := db.Exec(fmt.Sprintf(`
+	  CREATE UNIQUE INDEX IF NOT EXISTS %s_one_pending_per_user
+	  ON %s (item_id, user_id)
+	  WHERE status = 'pending';
+	`, models.BorrowRequestTable, models.BorrowRequestTable)).Error; err != nil {
+		return err
+	}
+
+	// 同一父级下分类名唯一（不区分大小写；顶级的 parent_id 为 NULL）
+	if err := db.Exec(fmt.Sprintf(`
+	  CREATE UNIQUE INDEX IF NOT EXISTS %s_parent_name
+	  ON %s (COALESCE(parent_id::text, ''), LOWER(name));
+	`, models.CategoryTable, models.CategoryTable)).Error; err != nil {
+		return err
+	}
+
+	// 同一父级下位置名唯一
+	if err := db.Exec(fmt.Sprintf(`
+	  CREATE UNIQUE INDEX IF NOT EXISTS %s_parent_name
+	  ON %s (COALESCE(parent_id::text, ''), LOWER(name));
+	`, models.LocationTable, models.LocationTable)).Error; err != nil {
+		return err
+	}
+
+	// 搜索：三元组索引支撑 LIKE '%q%' 与相似度，tsvector 索引支撑词前缀匹配
+	if err := db.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm;`).Error; err != nil {
+		return err
+	}
+	searchIndexes := []struct{ table, name, expr string }{
+		{models.ItemTable, "serial_trgm", "LOWER(serial) gin_trgm_ops"},
+		{models.ItemTable, "name_trgm", "LOWER(name) gin_trgm_ops"},
+		{models.ItemTable, "search_tsv", "(" + searchVector("serial", "name") + ")"},
+		{"lsb_users", "username_trgm", "LOWER(username) gin_trgm_ops"},
+		{"lsb_users", "display_name_trgm", "LOWER(display_name) gin_trgm_ops"},
+		{"lsb_users", "search_tsv", "(" + searchVector("username", "display_name") + ")"},
+	}
+	for _, ix := range searchIndexes {
+		if err := db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_%s ON %s USING gin (%s);`,
+			ix.table, ix.name, ix.table, ix.expr)).Error; err != nil {
+			return err
+		}
+	}
+
 	// 查询当前借用更快
 	if err := db.Exec(fmt.Sprintf(`
 	  CREATE INDEX IF NOT EXISTS %s_open_item_borrowedat_desc
@@ -62,3 +182,15 @@ func Migrate(db *gorm.DB) error {
 
 	return nil
 }
+
+// isExclusionViolation Postgres 排他约束冲突（23P01）
+func isExclusionViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23P01"
+}
+
+// isUniqueViolation Postgres 唯一约束冲突（23505）
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
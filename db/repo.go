@@ -1,16 +1,20 @@
 package db
 
 import (
+	"Gin_postgres_redis_rent_tool/cart"
 	"Gin_postgres_redis_rent_tool/models"
 	"context"
 	"errors"
-	"strings"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
-type Repo struct{ DB *gorm.DB }
+type Repo struct {
+	DB *gorm.DB
+	// Cart 购物车占用；借出时在事务内校验，为空（如 worker）则不校验
+	Cart *cart.Store
+}
 
 func NewRepo(db *gorm.DB) *Repo { return &Repo{DB: db} }
 
@@ -88,9 +92,9 @@ func (r *Repo) ListUsers(ctx context.Context, q string, page, size int) (ListUse
 	}
 
 	tx := r.DB.WithContext(ctx).Model(&models.User{})
-	if q = strings.TrimSpace(q); q != "" {
-		like := "%" + strings.ToLower(q) + "%"
-		tx = tx.Where("LOWER(username) LIKE ? OR LOWER(display_name) LIKE ?", like, like)
+	search := newTextSearch(q)
+	if search != nil {
+		tx = search.where(tx, userSearchCols(""))
 	}
 
 	var total int64
@@ -98,9 +102,14 @@ func (r *Repo) ListUsers(ctx context.Context, q string, page, size int) (ListUse
 		return ListUsersResult{}, err
 	}
 
+	// 有搜索词时按相关度排序
+	if search != nil {
+		tx = search.order(tx, userSearchCols(""), "created_at DESC")
+	} else {
+		tx = tx.Order("created_at DESC")
+	}
 	var users []models.User
 	if err := tx.
-		Order("created_at DESC").
 		Offset((page - 1) * size).
 		Limit(size).
 		Find(&users).Error; err != nil {
@@ -0,0 +1,41 @@
+// db/repo_attachment.go
+package db
+
+import (
+	"context"
+
+	"Gin_postgres_redis_rent_tool/models"
+)
+
+func (r *Repo) CreateAttachment(ctx context.Context, a *models.Attachment) error {
+	return r.DB.WithContext(ctx).Create(a).Error
+}
+
+func (r *Repo) FindAttachment(ctx context.Context, id string) (*models.Attachment, error) {
+	var a models.Attachment
+	if err := r.DB.WithContext(ctx).First(&a, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *Repo) ListAttachments(ctx context.Context, ownerType, ownerID string) ([]models.Attachment, error) {
+	as := []models.Attachment{}
+	err := r.DB.WithContext(ctx).
+		Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+		Order("created_at ASC").
+		Find(&as).Error
+	return as, err
+}
+
+// DeleteAttachment 删元数据并返回被删的记录，blob 由调用方清理
+func (r *Repo) DeleteAttachment(ctx context.Context, id string) (*models.Attachment, error) {
+	a, err := r.FindAttachment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.DB.WithContext(ctx).Delete(&models.Attachment{}, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return a, nil
+}
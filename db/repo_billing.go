@@ -0,0 +1,349 @@
+// db/repo_billing.go
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidLedger = errors.New("invalid ledger entry")
+	ErrNotWaivable   = errors.New("only rental and late fee charges can be waived")
+	ErrAlreadyWaived = errors.New("charge already waived")
+	ErrInvalidPeriod = errors.New("period must be YYYY-MM and not in the future")
+)
+
+const (
+	defaultCurrency   = "CNY"
+	billingPeriodForm = "2006-01" // 账期：自然月
+)
+
+// 账本币种：BILLING_CURRENCY，默认 CNY（只做记录，不做换算）
+func billingCurrency() string {
+	if v := strings.ToUpper(strings.TrimSpace(os.Getenv("BILLING_CURRENCY"))); len(v) == 3 {
+		return v
+	}
+	return defaultCurrency
+}
+
+func billingUnitDuration(unit string) time.Duration {
+	if unit == models.BillingUnitHour {
+		return time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// billingUnits 时长按计费单位向上取整
+func billingUnits(d, unit time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + unit - 1) / unit)
+}
+
+// snapshotPricing 借出时把当前生效的价格记到借用上（调用方在 Create 之前调用）
+func snapshotPricing(tx *gorm.DB, l *models.Loan) error {
+	pol, err := loadEffectivePolicy(tx, l.ItemID)
+	if err != nil {
+		return err
+	}
+	l.RateCents, l.LateFeeCents, l.BillingUnit = &pol.RateCents, &pol.LateFeeCents, &pol.BillingUnit
+	return nil
+}
+
+// loanPricing 借出时记下的价格；没有记录的旧借用按现行策略
+func loanPricing(tx *gorm.DB, l *models.Loan) (rate, lateFee int64, unit string, err error) {
+	if l.RateCents != nil && l.LateFeeCents != nil && l.BillingUnit != nil {
+		return *l.RateCents, *l.LateFeeCents, *l.BillingUnit, nil
+	}
+	pol, err := loadEffectivePolicy(tx, l.ItemID)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	return pol.RateCents, pol.LateFeeCents, pol.BillingUnit, nil
+}
+
+// chargeClosedLoan 借用关闭时按借出时记下的价格记租金与逾期费（调用方在同一事务内已关闭借用）；
+// 租金至少按一个计费单位，逾期费从 dueAt 起算
+func chargeClosedLoan(tx *gorm.DB, l *models.Loan, closedAt time.Time) error {
+	rate, lateFee, unitName, err := loanPricing(tx, l)
+	if err != nil {
+		return err
+	}
+	unit := billingUnitDuration(unitName)
+	cur := billingCurrency()
+	var entries []models.LedgerEntry
+	if rate > 0 {
+		n := billingUnits(closedAt.Sub(l.BorrowedAt), unit)
+		if n < 1 {
+			n = 1
+		}
+		entries = append(entries, models.LedgerEntry{
+			Kind:        models.LedgerRental,
+			AmountCents: n * rate,
+			Description: fmt.Sprintf("rental: %d %s × %d", n, unitName, rate),
+		})
+	}
+	if lateFee > 0 && l.DueAt != nil && closedAt.After(*l.DueAt) {
+		n := billingUnits(closedAt.Sub(*l.DueAt), unit)
+		entries = append(entries, models.LedgerEntry{
+			Kind:        models.LedgerLateFee,
+			AmountCents: n * lateFee,
+			Description: fmt.Sprintf("late fee: %d %s × %d", n, unitName, lateFee),
+		})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	for i := range entries {
+		entries[i].ID = uuid.NewString()
+		entries[i].UserID = l.UserID
+		entries[i].Currency = cur
+		entries[i].LoanID = &l.ID
+		entries[i].CreatedAt = closedAt
+	}
+	return tx.Create(&entries).Error
+}
+
+type RecordPaymentInput struct {
+	UserID      string
+	AmountCents int64
+	Method      string // 现金、转账等，自由填写
+	Note        string
+	ActorID     string
+}
+
+// RecordPayment 管理员手工登记一笔付款
+func (r *Repo) RecordPayment(ctx context.Context, in RecordPaymentInput) (*models.LedgerEntry, error) {
+	if in.AmountCents <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidLedger)
+	}
+	if _, err := r.FindUserByID(ctx, in.UserID); err != nil {
+		return nil, err
+	}
+	desc := "payment"
+	if m := strings.TrimSpace(in.Method); m != "" {
+		desc += " (" + m + ")"
+	}
+	if n := strings.TrimSpace(in.Note); n != "" {
+		desc += ": " + n
+	}
+	desc = clipRunes(desc, 255)
+	e := &models.LedgerEntry{
+		ID:          uuid.NewString(),
+		UserID:      in.UserID,
+		Kind:        models.LedgerPayment,
+		AmountCents: -in.AmountCents,
+		Currency:    billingCurrency(),
+		Description: desc,
+		CreatedBy:   &in.ActorID,
+	}
+	if err := r.DB.WithContext(ctx).Create(e).Error; err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// WaiveCharge 减免一笔费用：记一条等额 credit 并在原费用上标记，原费用不删除
+func (r *Repo) WaiveCharge(ctx context.Context, chargeID, reason, actorID string) (*models.LedgerEntry, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidLedger)
+	}
+	reason = clipRunes(reason, 240)
+	var credit *models.LedgerEntry
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ch models.LedgerEntry
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ch, "id = ?", chargeID).Error; err != nil {
+			return err
+		}
+		if !models.IsLedgerCharge(ch.Kind) {
+			return ErrNotWaivable
+		}
+		if ch.WaivedAt != nil {
+			return ErrAlreadyWaived
+		}
+		now := time.Now().UTC()
+		credit = &models.LedgerEntry{
+			ID:          uuid.NewString(),
+			UserID:      ch.UserID,
+			Kind:        models.LedgerCredit,
+			AmountCents: -ch.AmountCents,
+			Currency:    ch.Currency,
+			LoanID:      ch.LoanID,
+			RefID:       &ch.ID,
+			Description: "waived: " + reason,
+			CreatedBy:   &actorID,
+			CreatedAt:   now,
+		}
+		if err := tx.Create(credit).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.LedgerEntry{}).Where("id = ?", ch.ID).
+			Updates(map[string]any{"waived_at": now, "waived_by": actorID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return credit, nil
+}
+
+type LedgerRow struct {
+	models.LedgerEntry
+	Username string  `json:"username"`
+	Serial   *string `json:"serial,omitempty"`
+	ItemName *string `json:"itemName,omitempty"`
+}
+
+type LedgerQuery struct {
+	UserID string
+	Kind   string
+	From   *time.Time // 含
+	To     *time.Time // 不含
+}
+
+func (r *Repo) ledgerQuery(ctx context.Context, q LedgerQuery) *gorm.DB {
+	tx := r.DB.WithContext(ctx).
+		Table(models.LedgerEntryTable + " e").
+		Select("e.*, u.username, i.serial, i.name AS item_name").
+		Joins("LEFT JOIN lsb_users u ON u.id = e.user_id").
+		Joins("LEFT JOIN " + models.LoanTable + " l ON l.id = e.loan_id").
+		Joins("LEFT JOIN " + models.ItemTable + " i ON i.id = l.item_id")
+	if q.UserID != "" {
+		tx = tx.Where("e.user_id = ?", q.UserID)
+	}
+	if q.Kind != "" {
+		tx = tx.Where("e.kind = ?", q.Kind)
+	}
+	if q.From != nil {
+		tx = tx.Where("e.created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		tx = tx.Where("e.created_at < ?", *q.To)
+	}
+	return tx
+}
+
+// ListLedger 最近 500 条，新的在前
+func (r *Repo) ListLedger(ctx context.Context, q LedgerQuery) ([]LedgerRow, error) {
+	var rows []LedgerRow
+	err := r.ledgerQuery(ctx, q).Order("e.created_at DESC, e.id").Limit(500).Scan(&rows).Error
+	return rows, err
+}
+
+// UserBalance 当前余额（分），正数为欠款；before 不为空时只算此前的账目
+func (r *Repo) UserBalance(ctx context.Context, userID string, before *time.Time) (int64, error) {
+	var sum int64
+	tx := r.DB.WithContext(ctx).Model(&models.LedgerEntry{}).
+		Select("COALESCE(SUM(amount_cents), 0)").
+		Where("user_id = ?", userID)
+	if before != nil {
+		tx = tx.Where("created_at < ?", *before)
+	}
+	err := tx.Scan(&sum).Error
+	return sum, err
+}
+
+// Invoice 某用户一个账期（自然月，UTC）的账单；随时按账本现算，同一账期结果稳定
+type Invoice struct {
+	Number              string      `json:"number"`
+	Period              string      `json:"period"`
+	PeriodStart         time.Time   `json:"periodStart"`
+	PeriodEnd           time.Time   `json:"periodEnd"`
+	UserID              string      `json:"userId"`
+	Username            string      `json:"username"`
+	DisplayName         string      `json:"displayName"`
+	Currency            string      `json:"currency"`
+	OpeningBalanceCents int64       `json:"openingBalanceCents"`
+	ChargesCents        int64       `json:"chargesCents"`
+	CreditsCents        int64       `json:"creditsCents"`
+	PaymentsCents       int64       `json:"paymentsCents"`
+	ClosingBalanceCents int64       `json:"closingBalanceCents"`
+	Lines               []LedgerRow `json:"lines"`
+	GeneratedAt         time.Time   `json:"generatedAt"`
+}
+
+// invoiceCurrency 本期有账目时取其币种，否则取此前最近一笔；从未记账才用当前配置
+func (r *Repo) invoiceCurrency(ctx context.Context, userID string, lines []LedgerRow, end time.Time) (string, error) {
+	if len(lines) > 0 {
+		return lines[0].Currency, nil
+	}
+	var cur []string
+	if err := r.DB.WithContext(ctx).Model(&models.LedgerEntry{}).
+		Where("user_id = ? AND created_at < ?", userID, end).
+		Order("created_at DESC").Limit(1).
+		Pluck("currency", &cur).Error; err != nil {
+		return "", err
+	}
+	if len(cur) > 0 {
+		return cur[0], nil
+	}
+	return billingCurrency(), nil
+}
+
+// BuildInvoice period 为 "YYYY-MM"；当月账单截至生成时刻
+func (r *Repo) BuildInvoice(ctx context.Context, userID, period string) (*Invoice, error) {
+	start, err := time.Parse(billingPeriodForm, period)
+	if err != nil {
+		return nil, ErrInvalidPeriod
+	}
+	now := time.Now().UTC()
+	if start.After(now) {
+		return nil, ErrInvalidPeriod
+	}
+	end := start.AddDate(0, 1, 0)
+	u, err := r.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	opening, err := r.UserBalance(ctx, userID, &start)
+	if err != nil {
+		return nil, err
+	}
+	lines := []LedgerRow{}
+	if err := r.ledgerQuery(ctx, LedgerQuery{UserID: userID, From: &start, To: &end}).
+		Order("e.created_at ASC, e.id").
+		Scan(&lines).Error; err != nil {
+		return nil, err
+	}
+	// 币种取账目记录时的币种；改了 BILLING_CURRENCY 不会改写旧账单
+	currency, err := r.invoiceCurrency(ctx, userID, lines, end)
+	if err != nil {
+		return nil, err
+	}
+	inv := &Invoice{
+		Number:              fmt.Sprintf("INV-%s-%s", start.Format("200601"), strings.ToUpper(strings.ReplaceAll(userID, "-", "")[:8])),
+		Period:              start.Format(billingPeriodForm),
+		PeriodStart:         start,
+		PeriodEnd:           end,
+		UserID:              u.ID,
+		Username:            u.Username,
+		DisplayName:         u.DisplayName,
+		Currency:            currency,
+		OpeningBalanceCents: opening,
+		Lines:               lines,
+		GeneratedAt:         now,
+	}
+	for _, l := range lines {
+		switch l.Kind {
+		case models.LedgerPayment:
+			inv.PaymentsCents += l.AmountCents
+		case models.LedgerCredit:
+			inv.CreditsCents += l.AmountCents
+		default:
+			inv.ChargesCents += l.AmountCents
+		}
+	}
+	inv.ClosingBalanceCents = opening + inv.ChargesCents + inv.CreditsCents + inv.PaymentsCents
+	return inv, nil
+}
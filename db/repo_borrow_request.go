@@ -0,0 +1,245 @@
+// db/repo_borrow_request.go
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrApprovalRequired = errors.New("item requires approval to borrow")
+	ErrRequestPending   = errors.New("a borrow request for this item is already pending")
+	ErrRequestClosed    = errors.New("borrow request is no longer pending")
+	ErrInvalidRequest   = errors.New("invalid borrow request")
+)
+
+// 申请处理时限：BORROW_REQUEST_EXPIRE_HOURS，默认 48 小时
+func borrowRequestTTL() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("BORROW_REQUEST_EXPIRE_HOURS")); err == nil && v > 0 {
+		return time.Duration(v) * time.Hour
+	}
+	return 48 * time.Hour
+}
+
+// checkApproval 需审批的物品不能自助借出（调用方已锁住 item 行）
+func checkApproval(tx *gorm.DB, itemID string) error {
+	pol, err := loadEffectivePolicy(tx, itemID)
+	if err != nil {
+		return err
+	}
+	if pol.RequiresApproval {
+		return ErrApprovalRequired
+	}
+	return nil
+}
+
+// expireBorrowRequests 超时未处理的 pending → expired
+func expireBorrowRequests(tx *gorm.DB, now time.Time) error {
+	return tx.Model(&models.BorrowRequest{}).
+		Where("status = ? AND expires_at <= ?", models.RequestPending, now).
+		Updates(map[string]any{"status": models.RequestExpired, "updated_at": now}).Error
+}
+
+// CreateBorrowRequest 为需审批的物品提交借用申请；不需审批的物品应直接借
+func (r *Repo) CreateBorrowRequest(ctx context.Context, userID, itemID string, dueAt *time.Time, note string) (*models.BorrowRequest, error) {
+	var req *models.BorrowRequest
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		if err := expireBorrowRequests(tx, now); err != nil {
+			return err
+		}
+		var it models.Item
+		if err := tx.First(&it, "id = ?", itemID).Error; err != nil {
+			return err
+		}
+		if it.Status != models.ItemStatusActive || it.ArchivedAt != nil {
+			return ErrItemNotAvailable
+		}
+		if err := checkApproval(tx, it.ID); !errors.Is(err, ErrApprovalRequired) {
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: item can be borrowed directly", ErrInvalidRequest)
+		}
+		if dueAt != nil && !dueAt.After(now) {
+			return fmt.Errorf("%w: dueAt must be in the future", ErrInvalidRequest)
+		}
+		req = &models.BorrowRequest{
+			ID:        uuid.NewString(),
+			ItemID:    it.ID,
+			UserID:    userID,
+			DueAt:     dueAt,
+			Note:      strings.TrimSpace(note),
+			Status:    models.RequestPending,
+			ExpiresAt: now.Add(borrowRequestTTL()),
+		}
+		if err := tx.Create(req).Error; err != nil {
+			if isUniqueViolation(err) {
+				return ErrRequestPending
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func lockPendingRequest(tx *gorm.DB, id string, now time.Time) (*models.BorrowRequest, error) {
+	if err := expireBorrowRequests(tx, now); err != nil {
+		return nil, err
+	}
+	var req models.BorrowRequest
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&req, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if req.Status != models.RequestPending {
+		return nil, fmt.Errorf("%w: %s", ErrRequestClosed, req.Status)
+	}
+	return &req, nil
+}
+
+// ApproveBorrowRequest 批准并借出：锁住物品后走与 BorrowItem 相同的 checkoutLocked；
+// 借不出（已被借走、策略不允许等）时整体回滚，申请保持 pending。dueAt 不为空时覆盖申请的到期时间
+func (r *Repo) ApproveBorrowRequest(ctx context.Context, id, adminID, reason string, dueAt *time.Time) (*models.BorrowRequest, *models.Loan, error) {
+	var req *models.BorrowRequest
+	var loan *models.Loan
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		var err error
+		if req, err = lockPendingRequest(tx, id, now); err != nil {
+			return err
+		}
+		var it models.Item
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&it, "id = ? AND status = 'active' AND archived_at IS NULL", req.ItemID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrItemNotAvailable
+			}
+			return err
+		}
+		if dueAt == nil {
+			dueAt = req.DueAt
+		}
+		if loan, err = r.checkoutLocked(tx, &it, req.UserID, now, dueAt, req.Note, nil); err != nil {
+			return err
+		}
+		req.Status = models.RequestApproved
+		req.DecidedBy = &adminID
+		req.DecidedAt = &now
+		req.Reason = strings.TrimSpace(reason)
+		req.LoanID = &loan.ID
+		req.UpdatedAt = now
+		return tx.Save(req).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, loan, nil
+}
+
+// RejectBorrowRequest 驳回，必须写明原因
+func (r *Repo) RejectBorrowRequest(ctx context.Context, id, adminID, reason string) (*models.BorrowRequest, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidRequest)
+	}
+	return r.closeBorrowRequest(ctx, id, func(req *models.BorrowRequest, now time.Time) error {
+		req.Status = models.RequestRejected
+		req.DecidedBy = &adminID
+		req.DecidedAt = &now
+		req.Reason = reason
+		return nil
+	})
+}
+
+// CancelBorrowRequest 申请人撤回
+func (r *Repo) CancelBorrowRequest(ctx context.Context, id, userID string) (*models.BorrowRequest, error) {
+	return r.closeBorrowRequest(ctx, id, func(req *models.BorrowRequest, now time.Time) error {
+		if req.UserID != userID {
+			return gorm.ErrRecordNotFound
+		}
+		req.Status = models.RequestCancelled
+		return nil
+	})
+}
+
+func (r *Repo) closeBorrowRequest(ctx context.Context, id string, apply func(*models.BorrowRequest, time.Time) error) (*models.BorrowRequest, error) {
+	var req *models.BorrowRequest
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		var err error
+		if req, err = lockPendingRequest(tx, id, now); err != nil {
+			return err
+		}
+		if err := apply(req, now); err != nil {
+			return err
+		}
+		req.UpdatedAt = now
+		return tx.Save(req).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+type BorrowRequestRow struct {
+	models.BorrowRequest
+	Serial            string  `json:"serial"`
+	Name              string  `json:"name"`
+	InUse             bool    `json:"inUse"`
+	Username          string  `json:"username"`
+	DisplayName       string  `json:"displayName"`
+	DecidedByUsername *string `json:"decidedByUsername,omitempty"`
+}
+
+type BorrowRequestsQuery struct {
+	UserID string
+	ItemID string
+	Status string // 为空则全部
+}
+
+// ListBorrowRequests 审批队列：pending 按提交先后，其余新的在前；最多 200 条
+func (r *Repo) ListBorrowRequests(ctx context.Context, q BorrowRequestsQuery) ([]BorrowRequestRow, error) {
+	if err := expireBorrowRequests(r.DB.WithContext(ctx), time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	tx := r.DB.WithContext(ctx).
+		Table(models.BorrowRequestTable + " br").
+		Select(`br.*, i.serial, i.name, i.in_use,
+			u.username, u.display_name, du.username AS decided_by_username`).
+		Joins("JOIN " + models.ItemTable + " i ON i.id = br.item_id").
+		Joins("JOIN lsb_users u ON u.id = br.user_id").
+		Joins("LEFT JOIN lsb_users du ON du.id = br.decided_by")
+	if q.UserID != "" {
+		tx = tx.Where("br.user_id = ?", q.UserID)
+	}
+	if q.ItemID != "" {
+		tx = tx.Where("br.item_id = ?", q.ItemID)
+	}
+	if q.Status != "" {
+		tx = tx.Where("br.status = ?", q.Status)
+	}
+	if q.Status == models.RequestPending {
+		tx = tx.Order("br.created_at ASC")
+	} else {
+		tx = tx.Order("br.created_at DESC")
+	}
+	var rows []BorrowRequestRow
+	err := tx.Limit(200).Scan(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,353 @@
+// db/repo_category.go
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidCategory  = errors.New("invalid category")
+	ErrCategoryExists   = errors.New("a category with this name already exists here")
+	ErrCategoryCycle    = errors.New("category cannot be moved under itself")
+	ErrCategoryNotEmpty = errors.New("category has subcategories")
+)
+
+// 某分类及其全部子孙分类的 id（参数：分类 id）；UNION 去重，脏数据成环也不会死循环
+const categorySubtreeSQL = `WITH RECURSIVE sub AS (
+	SELECT id FROM ` + models.CategoryTable + ` WHERE id = ?
+	UNION
+	SELECT c.id FROM ` + models.CategoryTable + ` c JOIN sub ON c.parent_id = sub.id
+) SELECT id FROM sub`
+
+// 物品所在分类及其祖先（参数：物品 id），depth 0 为物品直属分类
+const itemCategoryChainSQL = `WITH RECURSIVE up AS (
+	SELECT c.id, c.parent_id, 0 AS depth
+	FROM ` + models.CategoryTable + ` c JOIN ` + models.ItemTable + ` i ON i.category_id = c.id
+	WHERE i.id = ?
+	UNION ALL
+	SELECT c.id, c.parent_id, up.depth + 1
+	FROM ` + models.CategoryTable + ` c JOIN up ON c.id = up.parent_id
+	WHERE up.depth < 32
+) SELECT id FROM up ORDER BY depth`
+
+// itemCategoryChain 由近到远：直属分类 → 父 → … → 顶级
+func itemCategoryChain(tx *gorm.DB, itemID string) ([]string, error) {
+	var ids []string
+	err := tx.Raw(itemCategoryChainSQL, itemID).Scan(&ids).Error
+	return ids, err
+}
+
+// NormalizeTags 去空白、转小写、去重
+func NormalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || len(t) > 64 || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func replaceItemTags(tx *gorm.DB, itemID string, tags []string) error {
+	if err := tx.Where("item_id = ?", itemID).Delete(&models.ItemTag{}).Error; err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	rows := make([]models.ItemTag, 0, len(tags))
+	for _, t := range tags {
+		rows = append(rows, models.ItemTag{ItemID: itemID, Tag: t})
+	}
+	return tx.Create(&rows).Error
+}
+
+// attachItemTags 批量把标签挂到物品上
+func attachItemTags(tx *gorm.DB, items []models.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]string, len(items))
+	for i := range items {
+		ids[i] = items[i].ID
+	}
+	var tags []models.ItemTag
+	if err := tx.Where("item_id IN ?", ids).Order("tag").Find(&tags).Error; err != nil {
+		return err
+	}
+	byItem := map[string][]string{}
+	for _, t := range tags {
+		byItem[t.ItemID] = append(byItem[t.ItemID], t.Tag)
+	}
+	for i := range items {
+		items[i].Tags = byItem[items[i].ID]
+	}
+	return nil
+}
+
+// ---------- 分类 ----------
+
+func (r *Repo) checkCategoryParent(tx *gorm.DB, id string, parentID *string) error {
+	if parentID == nil {
+		return nil
+	}
+	var n int64
+	if err := tx.Model(&models.Category{}).Where("id = ?", *parentID).Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: parent not found", ErrInvalidCategory)
+	}
+	if id == "" {
+		return nil
+	}
+	// 新父级不能是自己或自己的子孙
+	var inSubtree int64
+	if err := tx.Raw("SELECT COUNT(*) FROM ("+categorySubtreeSQL+") s WHERE s.id = ?", id, *parentID).
+		Scan(&inSubtree).Error; err != nil {
+		return err
+	}
+	if inSubtree > 0 {
+		return ErrCategoryCycle
+	}
+	return nil
+}
+
+func (r *Repo) CreateCategory(ctx context.Context, c *models.Category) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCategory)
+	}
+	if err := r.checkCategoryParent(r.DB.WithContext(ctx), "", c.ParentID); err != nil {
+		return err
+	}
+	c.ID = uuid.NewString()
+	if err := r.DB.WithContext(ctx).Create(c).Error; err != nil {
+		if isUniqueViolation(err) {
+			return ErrCategoryExists
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdateCategory 改名和/或移动到新父级
+func (r *Repo) UpdateCategory(ctx context.Context, id, name string, parentID *string) (*models.Category, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidCategory)
+	}
+	var c models.Category
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := r.checkCategoryParent(tx, id, parentID); err != nil {
+			return err
+		}
+		c.Name = name
+		c.ParentID = parentID
+		c.UpdatedAt = time.Now()
+		return tx.Save(&c).Error
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrCategoryExists
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// DeleteCategory 有子分类时拒绝；其下物品归到父级分类，分类级策略和属性定义一并删除
+func (r *Repo) DeleteCategory(ctx context.Context, id string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var c models.Category
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, "id = ?", id).Error; err != nil {
+			return err
+		}
+		var n int64
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", id).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrCategoryNotEmpty
+		}
+		if err := tx.Model(&models.Item{}).
+			Where("category_id = ?", id).
+			Update("category_id", c.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scope = ? AND scope_id = ?", models.PolicyScopeCategory, id).
+			Delete(&models.LoanPolicy{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("category_id = ?", id).Delete(&models.CategoryAttribute{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scope = ? AND scope_id = ?", models.PolicyScopeCategory, id).
+			Delete(&models.ChecklistItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Category{}, "id = ?", id).Error
+	})
+}
+
+type CategoryRow struct {
+	models.Category
+	Path      string `json:"path"`      // "电动工具 / 电钻"
+	ItemCount int64  `json:"itemCount"` // 含子分类
+}
+
+// ListCategories 扁平列表（按路径排序），前端按 parentId 组树
+func (r *Repo) ListCategories(ctx context.Context) ([]CategoryRow, error) {
+	var cs []models.Category
+	if err := r.DB.WithContext(ctx).Find(&cs).Error; err != nil {
+		return nil, err
+	}
+	type cnt struct {
+		CategoryID string
+		N          int64
+	}
+	var counts []cnt
+	if err := r.DB.WithContext(ctx).Model(&models.Item{}).
+		Select("category_id, COUNT(*) AS n").
+		Where("category_id IS NOT NULL").
+		Group("category_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	direct := map[string]int64{}
+	for _, c := range counts {
+		direct[c.CategoryID] = c.N
+	}
+
+	byID := map[string]models.Category{}
+	for _, c := range cs {
+		byID[c.ID] = c
+	}
+	rows := make([]CategoryRow, 0, len(cs))
+	for _, c := range cs {
+		parts := []string{c.Name}
+		for p, depth := c.ParentID, 0; p != nil && depth < 32; depth++ {
+			pc, ok := byID[*p]
+			if !ok {
+				break
+			}
+			parts = append([]string{pc.Name}, parts...)
+			p = pc.ParentID
+		}
+		rows = append(rows, CategoryRow{Category: c, Path: strings.Join(parts, " / ")})
+	}
+	// 物品数向上累加到所有祖先
+	idx := map[string]int{}
+	for i, row := range rows {
+		idx[row.ID] = i
+	}
+	for id, n := range direct {
+		for cur, depth := id, 0; depth < 32; depth++ {
+			i, ok := idx[cur]
+			if !ok {
+				break
+			}
+			rows[i].ItemCount += n
+			if rows[i].ParentID == nil {
+				break
+			}
+			cur = *rows[i].ParentID
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Path < rows[j].Path })
+	return rows, nil
+}
+
+// ---------- 物品的分类与标签 ----------
+
+// SetItemCategory 换分类时按新分类的 schema 重新校验已有属性，不满足则拒绝
+func (r *Repo) SetItemCategory(ctx context.Context, itemID string, categoryID *string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var it models.Item
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&it, "id = ?", itemID).Error; err != nil {
+			return err
+		}
+		if categoryID != nil {
+			var n int64
+			if err := tx.Model(&models.Category{}).Where("id = ?", *categoryID).Count(&n).Error; err != nil {
+				return err
+			}
+			if n == 0 {
+				return fmt.Errorf("%w: category not found", ErrInvalidCategory)
+			}
+		}
+		schema, err := loadAttributeSchema(tx, categoryID)
+		if err != nil {
+			return err
+		}
+		attrs, err := validateAttributes(schema, it.Attributes)
+		if err != nil {
+			return err
+		}
+		return bumpItem(tx, itemID, map[string]any{"category_id": categoryID, "attributes": attrs})
+	})
+}
+
+// SetItemTags 整体替换物品标签，返回归一化后的标签
+func (r *Repo) SetItemTags(ctx context.Context, itemID string, tags []string) ([]string, error) {
+	tags = NormalizeTags(tags)
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var it models.Item
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&it, "id = ?", itemID).Error; err != nil {
+			return err
+		}
+		if err := replaceItemTags(tx, itemID, tags); err != nil {
+			return err
+		}
+		return bumpItem(tx, itemID, nil)
+	})
+	return tags, err
+}
+
+func (r *Repo) ItemTags(ctx context.Context, itemID string) ([]string, error) {
+	var tags []string
+	err := r.DB.WithContext(ctx).Model(&models.ItemTag{}).
+		Where("item_id = ?", itemID).
+		Order("tag").
+		Pluck("tag", &tags).Error
+	return tags, err
+}
+
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// ListTags 所有在用标签及使用次数；prefix 用于输入联想
+func (r *Repo) ListTags(ctx context.Context, prefix string) ([]TagCount, error) {
+	tx := r.DB.WithContext(ctx).Model(&models.ItemTag{}).
+		Select("tag, COUNT(*) AS count").
+		Group("tag").
+		Order("count DESC, tag ASC").
+		Limit(200)
+	if p := strings.ToLower(strings.TrimSpace(prefix)); p != "" {
+		tx = tx.Where("tag LIKE ?", strings.NewReplacer("%", `\%`, "_", `\_`).Replace(p)+"%")
+	}
+	out := []TagCount{}
+	err := tx.Scan(&out).Error
+	return out, err
+}
@@ -0,0 +1,313 @@
+// db/repo_category_attribute.go
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidAttributeDef = errors.New("invalid attribute definition")
+	ErrAttributeExists     = errors.New("attribute already defined for this category")
+	ErrInvalidAttributes   = errors.New("item attributes do not match the category schema")
+)
+
+// AttributeError 属性校验失败，Problems 逐条列出
+type AttributeError struct {
+	Problems []string `json:"problems"`
+}
+
+func (e *AttributeError) Error() string {
+	return ErrInvalidAttributes.Error() + ": " + strings.Join(e.Problems, "; ")
+}
+func (e *AttributeError) Unwrap() error { return ErrInvalidAttributes }
+
+var attrNameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+
+// 某分类及其祖先（参数：分类 id），depth 0 为自身
+const categoryAncestorsSQL = `WITH RECURSIVE up AS (
+	SELECT id, parent_id, 0 AS depth FROM ` + models.CategoryTable + ` WHERE id = ?
+	UNION ALL
+	SELECT c.id, c.parent_id, up.depth + 1
+	FROM ` + models.CategoryTable + ` c JOIN up ON c.id = up.parent_id
+	WHERE up.depth < 32
+) SELECT id FROM up ORDER BY depth`
+
+// loadAttributeSchema 分类的有效属性定义（含祖先）；同名时近的覆盖远的
+func loadAttributeSchema(tx *gorm.DB, categoryID *string) ([]models.CategoryAttribute, error) {
+	if categoryID == nil {
+		return nil, nil
+	}
+	var chain []string
+	if err := tx.Raw(categoryAncestorsSQL, *categoryID).Scan(&chain).Error; err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	var defs []models.CategoryAttribute
+	if err := tx.Where("category_id IN ?", chain).Find(&defs).Error; err != nil {
+		return nil, err
+	}
+	depth := map[string]int{}
+	for i, id := range chain {
+		depth[id] = i
+	}
+	byName := map[string]models.CategoryAttribute{}
+	for _, d := range defs {
+		if cur, ok := byName[d.Name]; !ok || depth[d.CategoryID] < depth[cur.CategoryID] {
+			byName[d.Name] = d
+		}
+	}
+	out := make([]models.CategoryAttribute, 0, len(byName))
+	for _, d := range byName {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// coerceAttr 按类型校验并归一化；CSV 里来的字符串也能转成对应类型
+func coerceAttr(d models.CategoryAttribute, v any) (any, error) {
+	s, isStr := v.(string)
+	if isStr {
+		s = strings.TrimSpace(s)
+	}
+	switch d.Type {
+	case models.AttrTypeString:
+		if !isStr {
+			return nil, fmt.Errorf("must be a string")
+		}
+		return s, nil
+	case models.AttrTypeNumber, models.AttrTypeInteger:
+		var f float64
+		switch t := v.(type) {
+		case float64:
+			f = t
+		case json.Number:
+			n, err := t.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("must be a number")
+			}
+			f = n
+		case string:
+			n, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("must be a number")
+			}
+			f = n
+		default:
+			return nil, fmt.Errorf("must be a number")
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("must be a finite number")
+		}
+		if d.Type == models.AttrTypeInteger && f != math.Trunc(f) {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		return f, nil
+	case models.AttrTypeBoolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		if isStr {
+			switch strings.ToLower(s) {
+			case "true", "yes", "y", "1":
+				return true, nil
+			case "false", "no", "n", "0":
+				return false, nil
+			}
+		}
+		return nil, fmt.Errorf("must be true or false")
+	case models.AttrTypeDate:
+		if !isStr {
+			return nil, fmt.Errorf("must be a date (YYYY-MM-DD)")
+		}
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return nil, fmt.Errorf("must be a date (YYYY-MM-DD)")
+		}
+		return t.Format("2006-01-02"), nil
+	case models.AttrTypeEnum:
+		if !isStr {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(d.EnumValues, ", "))
+		}
+		for _, ev := range d.EnumValues {
+			if strings.EqualFold(ev, s) {
+				return ev, nil
+			}
+		}
+		return nil, fmt.Errorf("must be one of %s", strings.Join(d.EnumValues, ", "))
+	}
+	return nil, fmt.Errorf("unknown type %s", d.Type)
+}
+
+// validateAttributes 按 schema 校验；无 schema 时属性自由填写，有 schema 时不允许未定义的键
+func validateAttributes(schema []models.CategoryAttribute, attrs map[string]any) (models.JSONMap, error) {
+	out := models.JSONMap{}
+	var problems []string
+	defs := map[string]models.CategoryAttribute{}
+	for _, d := range schema {
+		defs[d.Name] = d
+	}
+	for k, v := range attrs {
+		if v == nil {
+			continue
+		}
+		if s, ok := v.(string); ok && strings.TrimSpace(s) == "" {
+			continue
+		}
+		d, ok := defs[k]
+		if !ok {
+			if len(schema) > 0 {
+				problems = append(problems, fmt.Sprintf("%s: not defined for this category", k))
+				continue
+			}
+			out[k] = v
+			continue
+		}
+		cv, err := coerceAttr(d, v)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", k, err))
+			continue
+		}
+		out[k] = cv
+	}
+	for _, d := range schema {
+		if _, ok := out[d.Name]; d.Required && !ok {
+			problems = append(problems, fmt.Sprintf("%s: required", d.Name))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, &AttributeError{Problems: problems}
+	}
+	return out, nil
+}
+
+func validateAttributeDef(d *models.CategoryAttribute) error {
+	d.Name = strings.TrimSpace(d.Name)
+	if !attrNameRe.MatchString(d.Name) {
+		return fmt.Errorf("%w: name must start with a letter and contain only letters, digits and _", ErrInvalidAttributeDef)
+	}
+	if !models.IsAttrType(d.Type) {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidAttributeDef, d.Type)
+	}
+	if d.Type == models.AttrTypeEnum {
+		vals := models.JSONStrings{}
+		seen := map[string]bool{}
+		for _, v := range d.EnumValues {
+			v = strings.TrimSpace(v)
+			if v != "" && !seen[strings.ToLower(v)] {
+				seen[strings.ToLower(v)] = true
+				vals = append(vals, v)
+			}
+		}
+		if len(vals) == 0 {
+			return fmt.Errorf("%w: enum needs at least one value", ErrInvalidAttributeDef)
+		}
+		d.EnumValues = vals
+	} else {
+		d.EnumValues = models.JSONStrings{}
+	}
+	return nil
+}
+
+// ---------- 管理接口 ----------
+
+// 新增必填属性不会回溯校验已有物品，下次编辑该物品时才要求补齐
+func (r *Repo) CreateCategoryAttribute(ctx context.Context, d *models.CategoryAttribute) error {
+	if err := validateAttributeDef(d); err != nil {
+		return err
+	}
+	if err := r.DB.WithContext(ctx).First(&models.Category{}, "id = ?", d.CategoryID).Error; err != nil {
+		return err
+	}
+	d.ID = uuid.NewString()
+	if err := r.DB.WithContext(ctx).Create(d).Error; err != nil {
+		if isUniqueViolation(err) {
+			return ErrAttributeExists
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdateCategoryAttribute 可改显示名、类型、必填、枚举值；键名不可改
+func (r *Repo) UpdateCategoryAttribute(ctx context.Context, id string, in models.CategoryAttribute) (*models.CategoryAttribute, error) {
+	var d models.CategoryAttribute
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&d, "id = ?", id).Error; err != nil {
+			return err
+		}
+		d.Label = in.Label
+		d.Type = in.Type
+		d.Required = in.Required
+		d.EnumValues = in.EnumValues
+		if err := validateAttributeDef(&d); err != nil {
+			return err
+		}
+		return tx.Save(&d).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *Repo) DeleteCategoryAttribute(ctx context.Context, id string) error {
+	res := r.DB.WithContext(ctx).Delete(&models.CategoryAttribute{}, "id = ?", id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CategoryAttributeSchema 分类的有效属性定义（含继承）
+func (r *Repo) CategoryAttributeSchema(ctx context.Context, categoryID string) ([]models.CategoryAttribute, error) {
+	if err := r.DB.WithContext(ctx).First(&models.Category{}, "id = ?", categoryID).Error; err != nil {
+		return nil, err
+	}
+	out, err := loadAttributeSchema(r.DB.WithContext(ctx), &categoryID)
+	if out == nil {
+		out = []models.CategoryAttribute{}
+	}
+	return out, err
+}
+
+// SetItemAttributes 整体替换物品属性，按当前分类的 schema 校验
+func (r *Repo) SetItemAttributes(ctx context.Context, itemID string, attrs map[string]any) (models.JSONMap, error) {
+	var clean models.JSONMap
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var it models.Item
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&it, "id = ?", itemID).Error; err != nil {
+			return err
+		}
+		schema, err := loadAttributeSchema(tx, it.CategoryID)
+		if err != nil {
+			return err
+		}
+		if clean, err = validateAttributes(schema, attrs); err != nil {
+			return err
+		}
+		return bumpItem(tx, itemID, map[string]any{"attributes": clean})
+	})
+	return clean, err
+}
@@ -0,0 +1,316 @@
+// db/repo_condition.go
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidChecklist  = errors.New("invalid checklist item")
+	ErrInvalidCondition  = errors.New("invalid condition report")
+	ErrConditionRecorded = errors.New("condition already recorded for this loan and stage")
+)
+
+// ConditionInput 借出 / 归还时提交的状况
+type ConditionInput struct {
+	Grade     string          `json:"grade"`     // good / fair / poor / damaged
+	Damage    string          `json:"damage"`    // 损坏描述，可空
+	Checklist map[string]bool `json:"checklist"` // 清单项 id → 是否齐全；物品有清单时每项都要填
+}
+
+// itemChecklist 物品的有效配件清单：从顶级分类到直属分类，最后是物品自己的
+func itemChecklist(tx *gorm.DB, itemID string) ([]models.ChecklistItem, error) {
+	chain, err := itemCategoryChain(tx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	var cs []models.ChecklistItem
+	q := tx.Where("scope = ? AND scope_id = ?", models.PolicyScopeItem, itemID)
+	if len(chain) > 0 {
+		q = q.Or("scope = ? AND scope_id IN ?", models.PolicyScopeCategory, chain)
+	}
+	if err := q.Find(&cs).Error; err != nil {
+		return nil, err
+	}
+	rank := map[string]int{itemID: len(chain)}
+	for i, id := range chain {
+		rank[id] = len(chain) - 1 - i
+	}
+	sort.SliceStable(cs, func(i, j int) bool {
+		if ri, rj := rank[cs[i].ScopeID], rank[cs[j].ScopeID]; ri != rj {
+			return ri < rj
+		}
+		if cs[i].Position != cs[j].Position {
+			return cs[i].Position < cs[j].Position
+		}
+		return cs[i].Label < cs[j].Label
+	})
+	return cs, nil
+}
+
+func (r *Repo) ItemChecklist(ctx context.Context, itemID string) ([]models.ChecklistItem, error) {
+	if _, err := r.FindItemByID(ctx, itemID); err != nil {
+		return nil, err
+	}
+	return itemChecklist(r.DB.WithContext(ctx), itemID)
+}
+
+// ListChecklistItems 某分类或物品自己定义的清单项（不含继承）
+func (r *Repo) ListChecklistItems(ctx context.Context, scope, scopeID string) ([]models.ChecklistItem, error) {
+	var cs []models.ChecklistItem
+	err := r.DB.WithContext(ctx).
+		Where("scope = ? AND scope_id = ?", scope, scopeID).
+		Order("position ASC, label ASC").
+		Find(&cs).Error
+	return cs, err
+}
+
+func (r *Repo) CreateChecklistItem(ctx context.Context, c *models.ChecklistItem) error {
+	c.Label = strings.TrimSpace(c.Label)
+	if c.Label == "" {
+		return fmt.Errorf("%w: label is required", ErrInvalidChecklist)
+	}
+	tx := r.DB.WithContext(ctx)
+	switch c.Scope {
+	case models.PolicyScopeItem:
+		if err := tx.First(&models.Item{}, "id = ?", c.ScopeID).Error; err != nil {
+			return err
+		}
+	case models.PolicyScopeCategory:
+		if err := tx.First(&models.Category{}, "id = ?", c.ScopeID).Error; err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: scope must be category or item", ErrInvalidChecklist)
+	}
+	c.ID = uuid.NewString()
+	return tx.Create(c).Error
+}
+
+// UpdateChecklistItem 只改名称和顺序；已有的状况记录保存的是当时的名称
+func (r *Repo) UpdateChecklistItem(ctx context.Context, id, label string, position int) (*models.ChecklistItem, error) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return nil, fmt.Errorf("%w: label is required", ErrInvalidChecklist)
+	}
+	var c models.ChecklistItem
+	if err := r.DB.WithContext(ctx).First(&c, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	c.Label = label
+	c.Position = position
+	c.UpdatedAt = time.Now()
+	if err := r.DB.WithContext(ctx).Save(&c).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *Repo) DeleteChecklistItem(ctx context.Context, id string) error {
+	res := r.DB.WithContext(ctx).Delete(&models.ChecklistItem{}, "id = ?", id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// buildConditionReport 校验等级与清单；每个清单项都必须回答，不认识的 id 报错
+func buildConditionReport(tx *gorm.DB, l *models.Loan, stage string, in *ConditionInput, actorID string) (*models.ConditionReport, error) {
+	if !models.IsConditionGrade(in.Grade) {
+		return nil, fmt.Errorf("%w: grade must be good, fair, poor or damaged", ErrInvalidCondition)
+	}
+	damage := strings.TrimSpace(in.Damage)
+	if len(damage) > 1000 {
+		return nil, fmt.Errorf("%w: damage description is too long", ErrInvalidCondition)
+	}
+	list, err := itemChecklist(tx, l.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	results := models.ChecklistResults{}
+	for _, c := range list {
+		present, ok := in.Checklist[c.ID]
+		if !ok {
+			return nil, fmt.Errorf("%w: checklist item %q not answered", ErrInvalidCondition, c.Label)
+		}
+		results = append(results, models.ChecklistResult{ChecklistItemID: c.ID, Label: c.Label, Present: present})
+	}
+	if len(in.Checklist) != len(list) {
+		return nil, fmt.Errorf("%w: unknown checklist item", ErrInvalidCondition)
+	}
+	rep := &models.ConditionReport{
+		ID:         uuid.NewString(),
+		LoanID:     l.ID,
+		Stage:      stage,
+		ItemID:     l.ItemID,
+		Grade:      in.Grade,
+		Damage:     damage,
+		Checklist:  results,
+		ReportedBy: actorID,
+	}
+	// 归还时有损坏或缺配件需要管理员处理
+	rep.Flagged = stage == models.ConditionAtReturn &&
+		(in.Grade == models.ConditionDamaged || damage != "" || len(rep.Missing()) > 0)
+	return rep, nil
+}
+
+// recordCondition 在借出 / 归还的事务内写状况记录；in 为空则不记录
+// 归还时被标记的物品若仍为 active 则转入 maintenance，等管理员检查
+func recordCondition(tx *gorm.DB, l *models.Loan, stage string, in *ConditionInput, actorID string, now time.Time) (*models.ConditionReport, error) {
+	if in == nil {
+		return nil, nil
+	}
+	rep, err := buildConditionReport(tx, l, stage, in, actorID)
+	if err != nil {
+		return nil, err
+	}
+	rep.CreatedAt = now
+	if err := tx.Create(rep).Error; err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrConditionRecorded
+		}
+		return nil, err
+	}
+	if !rep.Flagged {
+		return rep, nil
+	}
+	var it models.Item
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&it, "id = ?", l.ItemID).Error; err != nil {
+		return nil, err
+	}
+	if it.Status != models.ItemStatusActive {
+		return rep, nil
+	}
+	return rep, setItemStatus(tx, &it, models.ItemStatusMaintenance, conditionReason(rep), actorID, now)
+}
+
+// conditionReason 状态历史里的原因，截到列宽以内
+func conditionReason(rep *models.ConditionReport) string {
+	var parts []string
+	if rep.Grade == models.ConditionDamaged || rep.Damage != "" {
+		s := "returned damaged"
+		if rep.Damage != "" {
+			s += ": " + rep.Damage
+		}
+		parts = append(parts, s)
+	}
+	if missing := rep.Missing(); len(missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(missing, ", "))
+	}
+	return clipRunes(strings.Join(parts, "; "), 255)
+}
+
+// RecordCondition 事后补记：借出记录须在借用未归还时提交，归还记录须在归还之后
+func (r *Repo) RecordCondition(ctx context.Context, loanID, stage string, in ConditionInput, actorID string) (*models.ConditionReport, error) {
+	var rep *models.ConditionReport
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var l models.Loan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&l, "id = ?", loanID).Error; err != nil {
+			return err
+		}
+		switch stage {
+		case models.ConditionAtCheckout:
+			if l.ReturnedAt != nil {
+				return fmt.Errorf("%w: loan is already returned", ErrInvalidCondition)
+			}
+		case models.ConditionAtReturn:
+			if l.ReturnedAt == nil {
+				return fmt.Errorf("%w: loan is not returned yet", ErrInvalidCondition)
+			}
+		default:
+			return fmt.Errorf("%w: stage must be checkout or return", ErrInvalidCondition)
+		}
+		var err error
+		rep, err = recordCondition(tx, &l, stage, &in, actorID, time.Now().UTC())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+type ConditionReportRow struct {
+	models.ConditionReport
+	Serial           string `json:"serial"`
+	Name             string `json:"name"`
+	BorrowerUsername string `json:"borrowerUsername"`
+	ReporterUsername string `json:"reporterUsername"`
+}
+
+type ConditionReportsQuery struct {
+	LoanID      string
+	ItemID      string
+	FlaggedOnly bool
+}
+
+func conditionReportRows(tx *gorm.DB) *gorm.DB {
+	return tx.Table(models.ConditionReportTable + " cr").
+		Select(`cr.*, i.serial, i.name,
+			bu.username AS borrower_username,
+			ru.username AS reporter_username`).
+		Joins("JOIN " + models.LoanTable + " l ON l.id = cr.loan_id").
+		Joins("JOIN " + models.ItemTable + " i ON i.id = cr.item_id").
+		Joins("LEFT JOIN lsb_users bu ON bu.id = l.user_id").
+		Joins("LEFT JOIN lsb_users ru ON ru.id = cr.reported_by")
+}
+
+// ListConditionReports 按借用 / 物品查；都不填时返回最近 200 条
+func (r *Repo) ListConditionReports(ctx context.Context, q ConditionReportsQuery) ([]ConditionReportRow, error) {
+	tx := conditionReportRows(r.DB.WithContext(ctx))
+	if q.LoanID != "" {
+		tx = tx.Where("cr.loan_id = ?", q.LoanID)
+	}
+	if q.ItemID != "" {
+		tx = tx.Where("cr.item_id = ?", q.ItemID)
+	}
+	if q.FlaggedOnly {
+		tx = tx.Where("cr.flagged")
+	}
+	if q.LoanID == "" && q.ItemID == "" {
+		tx = tx.Limit(200)
+	}
+	var rows []ConditionReportRow
+	err := tx.Order("cr.created_at DESC").Scan(&rows).Error
+	return rows, err
+}
+
+// PendingConditionAlerts 已标记但尚未通知管理员的归还记录
+func (r *Repo) PendingConditionAlerts(ctx context.Context) ([]ConditionReportRow, error) {
+	var rows []ConditionReportRow
+	err := conditionReportRows(r.DB.WithContext(ctx)).
+		Where("cr.flagged AND cr.notified_at IS NULL").
+		Order("cr.created_at ASC").
+		Limit(100).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// MarkConditionNotified 占位：返回 false 表示别的副本已经处理
+func (r *Repo) MarkConditionNotified(ctx context.Context, id string, at time.Time) (bool, error) {
+	res := r.DB.WithContext(ctx).Model(&models.ConditionReport{}).
+		Where("id = ? AND notified_at IS NULL", id).
+		Update("notified_at", at)
+	return res.RowsAffected > 0, res.Error
+}
+
+// UnmarkConditionNotified 发送失败时撤回，下一轮重试
+func (r *Repo) UnmarkConditionNotified(ctx context.Context, id string) error {
+	return r.DB.WithContext(ctx).Model(&models.ConditionReport{}).
+		Where("id = ?", id).
+		Update("notified_at", nil).Error
+}
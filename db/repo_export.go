@@ -0,0 +1,128 @@
+// db/repo_export.go
+package db
+
+import (
+	"context"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"gorm.io/gorm"
+)
+
+// eachRow 用游标逐行扫描，避免整表读入内存
+func eachRow[T any](tx *gorm.DB, fn func(*T) error) error {
+	rows, err := tx.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v T
+		if err := tx.ScanRows(rows, &v); err != nil {
+			return err
+		}
+		if err := fn(&v); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EachAdminItem 与 ListItemsWithCurrentLoan 相同的过滤，不分页
+func (r *Repo) EachAdminItem(ctx context.Context, q AdminItemsQuery, fn func(*AdminItemRow) error) error {
+	qry := adminItemsOrder(adminItemsQuery(r.DB.WithContext(ctx), q), q)
+	return eachRow(qry, fn)
+}
+
+type LoanExportRow struct {
+	ID               string
+	ItemID           string
+	Serial           string
+	ItemName         string
+	CategoryName     *string
+	UserID           string
+	Username         string
+	BorrowedAt       time.Time
+	DueAt            *time.Time
+	ReturnedAt       *time.Time
+	ReturnedUsername *string
+	Outcome          string
+	RenewalCount     int
+	Note             string
+}
+
+type LoansExportQuery struct {
+	UserID     string
+	ItemID     string
+	Status     string // open / returned / overdue，或与 ListLoans 相同直接写结束方式
+	Outcome    string // returned / handed_over / lost / stolen / destroyed
+	CategoryID string // 含子分类
+}
+
+// EachLoan 与 ListLoans 相同的过滤，另可按分类；带物品编号、分类和借用人
+func (r *Repo) EachLoan(ctx context.Context, lq LoansExportQuery, fn func(*LoanExportRow) error) error {
+	q := r.DB.WithContext(ctx).
+		Table(models.LoanTable + " l").
+		Select(`l.id, l.item_id, i.serial, i.name AS item_name, cat.name AS category_name, l.user_id, u.username,
+			l.borrowed_at, l.due_at, l.returned_at, ru.username AS returned_username,
+			l.outcome, l.renewal_count, l.note`).
+		Joins("JOIN " + models.ItemTable + " i ON i.id = l.item_id").
+		Joins("LEFT JOIN " + models.CategoryTable + " cat ON cat.id = i.category_id").
+		Joins("LEFT JOIN lsb_users u ON u.id = l.user_id").
+		Joins("LEFT JOIN lsb_users ru ON ru.id = l.returned_by").
+		Order("l.borrowed_at DESC")
+	if lq.UserID != "" {
+		q = q.Where("l.user_id = ?", lq.UserID)
+	}
+	if lq.ItemID != "" {
+		q = q.Where("l.item_id = ?", lq.ItemID)
+	}
+	switch lq.Status {
+	case "":
+	case "open":
+		q = q.Where("l.returned_at IS NULL")
+	case "returned":
+		q = q.Where("l.returned_at IS NOT NULL")
+	case "overdue":
+		q = q.Where("l.returned_at IS NULL AND l.due_at IS NOT NULL AND l.due_at < NOW()")
+	default:
+		q = q.Where("l.outcome = ?", lq.Status)
+	}
+	if lq.Outcome != "" {
+		q = q.Where("l.outcome = ?", lq.Outcome)
+	}
+	q = itemCategoryTagFilter(q, lq.CategoryID, "")
+	return eachRow(q, fn)
+}
+
+type UserExportRow struct {
+	ID           string
+	Username     string
+	DisplayName  string
+	IsAdmin      bool
+	CreatedAt    time.Time
+	LastSeenAt   *time.Time
+	OpenLoans    int
+	OverdueLoans int
+	OpenSerials  string // 逗号分隔
+}
+
+// EachUserWithOpenLoans 与 ListUsersWithOpenLoans 相同的搜索，每个用户一行，未归还借用聚合成列
+func (r *Repo) EachUserWithOpenLoans(ctx context.Context, q string, fn func(*UserExportRow) error) error {
+	tx := r.DB.WithContext(ctx).
+		Table("lsb_users u").
+		Select(`u.id, u.username, u.display_name, u.is_admin, u.created_at, u.last_seen_at,
+			COUNT(l.id) AS open_loans,
+			COUNT(l.id) FILTER (WHERE l.due_at IS NOT NULL AND l.due_at < NOW()) AS overdue_loans,
+			COALESCE(STRING_AGG(i.serial, ',' ORDER BY l.borrowed_at DESC), '') AS open_serials`).
+		Joins("LEFT JOIN " + models.LoanTable + " l ON l.user_id = u.id AND l.returned_at IS NULL").
+		Joins("LEFT JOIN " + models.ItemTable + " i ON i.id = l.item_id").
+		Group("u.id")
+	if s := newTextSearch(q); s != nil {
+		tx = s.order(s.where(tx, userSearchCols("u.")), userSearchCols("u."), "u.created_at DESC")
+	} else {
+		tx = tx.Order("u.created_at DESC")
+	}
+	return eachRow(tx, fn)
+}
@@ -0,0 +1,280 @@
+// db/repo_handover.go
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrHandoverNotAllowed = errors.New("loan cannot be handed over")
+	ErrHandoverPending    = errors.New("a handover is already pending for this loan")
+	ErrHandoverClosed     = errors.New("handover is no longer pending")
+	ErrHandoverForbidden  = errors.New("not a party to this handover")
+)
+
+// 转交确认时限：HANDOVER_EXPIRE_MINUTES，默认 60 分钟
+func handoverTTL() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("HANDOVER_EXPIRE_MINUTES")); err == nil && v > 0 {
+		return time.Duration(v) * time.Minute
+	}
+	return 60 * time.Minute
+}
+
+// expireHandovers 过了确认时限的 pending → expired
+func expireHandovers(tx *gorm.DB, now time.Time) error {
+	return tx.Model(&models.LoanHandover{}).
+		Where("status = ? AND expires_at <= ?", models.HandoverPending, now).
+		Updates(map[string]any{"status": models.HandoverExpired, "updated_at": now}).Error
+}
+
+// cancelPendingHandovers 借用被正常归还时作废其待确认的转交
+func cancelPendingHandovers(tx *gorm.DB, loanID string, now time.Time) error {
+	return tx.Model(&models.LoanHandover{}).
+		Where("loan_id = ? AND status = ?", loanID, models.HandoverPending).
+		Updates(map[string]any{"status": models.HandoverCancelled, "responded_at": now, "updated_at": now}).Error
+}
+
+type StartHandoverInput struct {
+	LoanID   string
+	ActorID  string // 发起人：借用人本人，或管理员（ByAdmin）
+	ByAdmin  bool
+	ToUserID string
+	Note     string
+}
+
+// StartHandover 借用人发起转交，等接收人确认；成套借出的成员只能整套归还，不能单件转交
+func (r *Repo) StartHandover(ctx context.Context, in StartHandoverInput) (*models.LoanHandover, error) {
+	var h *models.LoanHandover
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		if err := expireHandovers(tx, now); err != nil {
+			return err
+		}
+		var l models.Loan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&l, "id = ?", in.LoanID).Error; err != nil {
+			return err
+		}
+		if l.UserID != in.ActorID && !in.ByAdmin {
+			return ErrHandoverForbidden
+		}
+		switch {
+		case l.ReturnedAt != nil:
+			return fmt.Errorf("%w: loan is already returned", ErrHandoverNotAllowed)
+		case l.KitCheckoutID != nil:
+			return fmt.Errorf("%w: kit items are returned as a whole", ErrHandoverNotAllowed)
+		case l.UserID == in.ToUserID:
+			return fmt.Errorf("%w: recipient already holds this item", ErrHandoverNotAllowed)
+		}
+		// 需审批的物品不能私下转交，接收人须自行提交借用申请
+		if err := checkApproval(tx, l.ItemID); err != nil {
+			if errors.Is(err, ErrApprovalRequired) {
+				return fmt.Errorf("%w: item requires approval to borrow", ErrHandoverNotAllowed)
+			}
+			return err
+		}
+		h = &models.LoanHandover{
+			ID:         uuid.NewString(),
+			LoanID:     l.ID,
+			ItemID:     l.ItemID,
+			FromUserID: l.UserID,
+			ToUserID:   in.ToUserID,
+			Status:     models.HandoverPending,
+			Note:       strings.TrimSpace(in.Note),
+			ExpiresAt:  now.Add(handoverTTL()),
+		}
+		if err := tx.Create(h).Error; err != nil {
+			if isUniqueViolation(err) {
+				return ErrHandoverPending
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// lockPendingHandover 锁住转交记录并确认仍待确认
+func lockPendingHandover(tx *gorm.DB, id string, now time.Time) (*models.LoanHandover, error) {
+	if err := expireHandovers(tx, now); err != nil {
+		return nil, err
+	}
+	var h models.LoanHandover
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&h, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if h.Status != models.HandoverPending {
+		return nil, fmt.Errorf("%w: %s", ErrHandoverClosed, h.Status)
+	}
+	return &h, nil
+}
+
+// AcceptHandover 接收人确认：同一事务里关闭转出方的借用并为接收人新开借用，物品始终处于借出状态。
+// 接收人按自己的身份走借用策略、保养检查与预约冲突检查；dueAt 为空时取默认借期
+func (r *Repo) AcceptHandover(ctx context.Context, id, actorID string, dueAt *time.Time) (*models.LoanHandover, *models.Loan, error) {
+	var h *models.LoanHandover
+	var loan *models.Loan
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		var err error
+		if h, err = lockPendingHandover(tx, id, now); err != nil {
+			return err
+		}
+		if h.ToUserID != actorID {
+			return ErrHandoverForbidden
+		}
+		// 与借出相同的加锁顺序：先物品，后借用
+		var it models.Item
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&it, "id = ?", h.ItemID).Error; err != nil {
+			return err
+		}
+		if it.Status != models.ItemStatusActive || it.ArchivedAt != nil {
+			return ErrItemNotAvailable
+		}
+		var old models.Loan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&old, "id = ?", h.LoanID).Error; err != nil {
+			return err
+		}
+		if old.ReturnedAt != nil || old.UserID != h.FromUserID {
+			return fmt.Errorf("%w: loan is already closed", ErrHandoverClosed)
+		}
+
+		if err := checkMaintenanceBlock(tx, it.ID); err != nil {
+			return err
+		}
+		// 同一物品只能有一条未归还借用（部分唯一索引），先关旧借用再开新借用
+		if err := tx.Model(&models.Loan{}).Where("id = ?", old.ID).Updates(map[string]any{
+			"returned_at": now,
+			"returned_by": h.FromUserID,
+			"handover_id": h.ID,
+			"outcome":     models.LoanOutcomeHandedOver,
+			"updated_at":  now,
+		}).Error; err != nil {
+			return err
+		}
+		// 转出方的借用到此结算
+		if err := chargeClosedLoan(tx, &old, now); err != nil {
+			return err
+		}
+		due, err := evaluateLoanPolicy(tx, it.ID, h.ToUserID, now, dueAt)
+		if err != nil {
+			return err
+		}
+		if err := checkReservationConflict(tx, it.ID, h.ToUserID, now, due); err != nil {
+			return err
+		}
+		loan = &models.Loan{
+			ID:         uuid.NewString(),
+			ItemID:     it.ID,
+			UserID:     h.ToUserID,
+			BorrowedAt: now,
+			DueAt:      due,
+			Note:       h.Note,
+			HandoverID: &h.ID,
+		}
+		if err := snapshotPricing(tx, loan); err != nil {
+			return err
+		}
+		if err := tx.Create(loan).Error; err != nil {
+			return err
+		}
+		h.Status = models.HandoverAccepted
+		h.NewLoanID = &loan.ID
+		h.RespondedAt = &now
+		h.UpdatedAt = now
+		return tx.Save(h).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return h, loan, nil
+}
+
+// DeclineHandover 接收人拒绝
+func (r *Repo) DeclineHandover(ctx context.Context, id, actorID string) (*models.LoanHandover, error) {
+	return r.closeHandover(ctx, id, models.HandoverDeclined, func(h *models.LoanHandover) bool {
+		return h.ToUserID == actorID
+	})
+}
+
+// CancelHandover 发起人（或管理员）撤回
+func (r *Repo) CancelHandover(ctx context.Context, id, actorID string, byAdmin bool) (*models.LoanHandover, error) {
+	return r.closeHandover(ctx, id, models.HandoverCancelled, func(h *models.LoanHandover) bool {
+		return byAdmin || h.FromUserID == actorID
+	})
+}
+
+func (r *Repo) closeHandover(ctx context.Context, id, status string, allowed func(*models.LoanHandover) bool) (*models.LoanHandover, error) {
+	var h *models.LoanHandover
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		var err error
+		if h, err = lockPendingHandover(tx, id, now); err != nil {
+			return err
+		}
+		if !allowed(h) {
+			return ErrHandoverForbidden
+		}
+		h.Status = status
+		h.RespondedAt = &now
+		h.UpdatedAt = now
+		return tx.Save(h).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+type HandoverRow struct {
+	models.LoanHandover
+	Serial       string `json:"serial"`
+	Name         string `json:"name"`
+	FromUsername string `json:"fromUsername"`
+	ToUsername   string `json:"toUsername"`
+}
+
+type HandoversQuery struct {
+	UserID string // 作为发起人或接收人
+	ItemID string
+	Status string // 为空则全部
+}
+
+// ListHandovers 最近 200 条，新的在前
+func (r *Repo) ListHandovers(ctx context.Context, q HandoversQuery) ([]HandoverRow, error) {
+	if err := expireHandovers(r.DB.WithContext(ctx), time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	tx := r.DB.WithContext(ctx).
+		Table(models.LoanHandoverTable + " h").
+		Select("h.*, i.serial, i.name, fu.username AS from_username, tu.username AS to_username").
+		Joins("JOIN " + models.ItemTable + " i ON i.id = h.item_id").
+		Joins("LEFT JOIN lsb_users fu ON fu.id = h.from_user_id").
+		Joins("LEFT JOIN lsb_users tu ON tu.id = h.to_user_id")
+	if q.UserID != "" {
+		tx = tx.Where("h.from_user_id = ? OR h.to_user_id = ?", q.UserID, q.UserID)
+	}
+	if q.ItemID != "" {
+		tx = tx.Where("h.item_id = ?", q.ItemID)
+	}
+	if q.Status != "" {
+		tx = tx.Where("h.status = ?", q.Status)
+	}
+	var rows []HandoverRow
+	err := tx.Order("h.created_at DESC").Limit(200).Scan(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,245 @@
+// db/repo_item_edit.go
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrVersionConflict = errors.New("item was modified by someone else")
+	ErrSerialTaken     = errors.New("serial already in use")
+	ErrItemArchived    = errors.New("item is archived")
+	ErrInvalidItem     = errors.New("invalid item")
+)
+
+// lockItemVersion 锁住物品行并核对版本号；version 为 0 表示不校验
+func lockItemVersion(tx *gorm.DB, itemID string, version int64) (*models.Item, error) {
+	var it models.Item
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&it, "id = ?", itemID).Error; err != nil {
+		return nil, err
+	}
+	if version != 0 && it.Version != version {
+		return &it, ErrVersionConflict
+	}
+	return &it, nil
+}
+
+// bumpItem 管理员编辑物品后统一 +1 版本号
+func bumpItem(tx *gorm.DB, itemID string, fields map[string]any) error {
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	fields["version"] = gorm.Expr("version + 1")
+	fields["updated_at"] = time.Now().UTC()
+	return tx.Model(&models.Item{}).Where("id = ?", itemID).Updates(fields).Error
+}
+
+// cancelItemBookings 取消物品的有效预约与排队（报废、归档时）
+func cancelItemBookings(tx *gorm.DB, itemID, actorID string, now time.Time) error {
+	if err := tx.Model(&models.Reservation{}).
+		Where("item_id = ? AND status = ? AND end_at > ?", itemID, models.ReservationConfirmed, now).
+		Updates(map[string]any{
+			"status":       models.ReservationCancelled,
+			"cancelled_at": now,
+			"cancelled_by": actorID,
+			"updated_at":   now,
+		}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.WaitlistEntry{}).
+		Where("item_id = ? AND status IN ?", itemID, []string{models.WaitlistWaiting, models.WaitlistOffered}).
+		Updates(map[string]any{"status": models.WaitlistCancelled, "updated_at": now}).Error
+}
+
+func hasOpenLoan(tx *gorm.DB, it *models.Item) (bool, error) {
+	if it.InUse {
+		return true, nil
+	}
+	var n int64
+	err := tx.Model(&models.Loan{}).Where("item_id = ? AND returned_at IS NULL", it.ID).Count(&n).Error
+	return n > 0, err
+}
+
+type UpdateItemInput struct {
+	ItemID  string
+	Version int64
+	Name    *string
+	Serial  *string
+}
+
+// UpdateItem 改名称/编号；版本号不一致返回 ErrVersionConflict
+func (r *Repo) UpdateItem(ctx context.Context, in UpdateItemInput) (*models.Item, error) {
+	fields := map[string]any{}
+	if in.Name != nil {
+		name := strings.TrimSpace(*in.Name)
+		if name == "" || len(name) > 200 {
+			return nil, fmt.Errorf("%w: name must be 1-200 characters", ErrInvalidItem)
+		}
+		fields["name"] = name
+	}
+	if in.Serial != nil {
+		serial := strings.TrimSpace(*in.Serial)
+		if serial == "" || len(serial) > 120 {
+			return nil, fmt.Errorf("%w: serial must be 1-120 characters", ErrInvalidItem)
+		}
+		fields["serial"] = serial
+	}
+	var out *models.Item
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		it, err := lockItemVersion(tx, in.ItemID, in.Version)
+		if err != nil {
+			return err
+		}
+		if len(fields) > 0 {
+			if err := bumpItem(tx, it.ID, fields); err != nil {
+				return err
+			}
+		}
+		out = &models.Item{}
+		return tx.First(out, "id = ?", it.ID).Error
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrSerialTaken
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// ArchiveItem 归档：须无未归还借用，同时取消有效预约与排队；借用历史保留
+func (r *Repo) ArchiveItem(ctx context.Context, itemID string, version int64, actorID string) (*models.Item, error) {
+	var out models.Item
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		it, err := lockItemVersion(tx, itemID, version)
+		if err != nil {
+			return err
+		}
+		if it.ArchivedAt != nil {
+			return ErrItemArchived
+		}
+		open, err := hasOpenLoan(tx, it)
+		if err != nil {
+			return err
+		}
+		if open {
+			return ErrItemHasOpenLoan
+		}
+		now := time.Now().UTC()
+		if err := cancelItemBookings(tx, it.ID, actorID, now); err != nil {
+			return err
+		}
+		if err := bumpItem(tx, it.ID, map[string]any{"archived_at": now}); err != nil {
+			return err
+		}
+		return tx.First(&out, "id = ?", it.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UnarchiveItem 取消归档
+func (r *Repo) UnarchiveItem(ctx context.Context, itemID string, version int64) (*models.Item, error) {
+	var out models.Item
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		it, err := lockItemVersion(tx, itemID, version)
+		if err != nil {
+			return err
+		}
+		if it.ArchivedAt != nil {
+			if err := bumpItem(tx, it.ID, map[string]any{"archived_at": nil}); err != nil {
+				return err
+			}
+		}
+		return tx.First(&out, "id = ?", it.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteItem 彻底删除物品及其全部关联数据（含已归还的借用记录）；有未归还借用时拒绝。
+// 返回需要清理文件的附件（物品附件与其借用的照片）
+func (r *Repo) DeleteItem(ctx context.Context, itemID string, version int64) ([]models.Attachment, error) {
+	var atts []models.Attachment
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		it, err := lockItemVersion(tx, itemID, version)
+		if err != nil {
+			return err
+		}
+		open, err := hasOpenLoan(tx, it)
+		if err != nil {
+			return err
+		}
+		if open {
+			return ErrItemHasOpenLoan
+		}
+
+		loanIDs := tx.Model(&models.Loan{}).Select("id").Where("item_id = ?", it.ID)
+		if err := tx.Where("(owner_type = ? AND owner_id = ?) OR (owner_type = ? AND owner_id IN (?))",
+			models.AttachOwnerItem, it.ID, models.AttachOwnerLoan, loanIDs).
+			Find(&atts).Error; err != nil {
+			return err
+		}
+		if len(atts) > 0 {
+			ids := make([]string, len(atts))
+			for i := range atts {
+				ids[i] = atts[i].ID
+			}
+			if err := tx.Delete(&models.Attachment{}, "id IN ?", ids).Error; err != nil {
+				return err
+			}
+		}
+		// 依赖 loanIDs 子查询的先删，最后删 loans
+		for _, del := range []struct {
+			model any
+			where string
+			arg   any
+		}{
+			{&models.LoanExtension{}, "loan_id IN (?)", loanIDs},
+			{&models.LoanNotification{}, "loan_id IN (?)", loanIDs},
+			{&models.ConditionReport{}, "loan_id IN (?)", loanIDs},
+			{&models.Loan{}, "item_id = ?", it.ID},
+			{&models.Reservation{}, "item_id = ?", it.ID},
+			{&models.WaitlistEntry{}, "item_id = ?", it.ID},
+			{&models.WorkOrder{}, "item_id = ?", it.ID},
+			{&models.MaintenancePlan{}, "item_id = ?", it.ID},
+			{&models.ItemStatusChange{}, "item_id = ?", it.ID},
+			{&models.ItemIdentifier{}, "item_id = ?", it.ID},
+			{&models.ItemTag{}, "item_id = ?", it.ID},
+			{&models.KitItem{}, "item_id = ?", it.ID},
+			{&models.LoanHandover{}, "item_id = ?", it.ID},
+			{&models.BorrowRequest{}, "item_id = ?", it.ID},
+			{&models.LoanLoss{}, "item_id = ?", it.ID},
+		} {
+			if err := tx.Where(del.where, del.arg).Delete(del.model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("scope = ? AND scope_id = ?", models.PolicyScopeItem, it.ID).
+			Delete(&models.LoanPolicy{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scope = ? AND scope_id = ?", models.PolicyScopeItem, it.ID).
+			Delete(&models.ChecklistItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Item{}, "id = ?", it.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return atts, nil
+}
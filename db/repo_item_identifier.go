@@ -0,0 +1,149 @@
+// db/repo_item_identifier.go
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownIdentifier = errors.New("no item matches this code")
+	ErrIdentifierTaken   = errors.New("identifier already belongs to another item")
+	ErrInvalidIdentifier = errors.New("invalid identifier")
+)
+
+// 标签二维码里的深链接形如 https://host/items/<uuid>
+func itemIDFromLink(code string) (string, bool) {
+	i := strings.LastIndex(code, "/items/")
+	if i < 0 {
+		return "", false
+	}
+	id := code[i+len("/items/"):]
+	if j := strings.IndexAny(id, "/?#"); j >= 0 {
+		id = id[:j]
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return "", false
+	}
+	return id, true
+}
+
+// ResolveItem 把扫到的任意编码解析成物品：深链接 / 内部 UUID / 别名表 / 物品编号
+func (r *Repo) ResolveItem(ctx context.Context, code string) (*models.Item, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, ErrUnknownIdentifier
+	}
+	db := r.DB.WithContext(ctx)
+
+	id, ok := itemIDFromLink(code)
+	if !ok {
+		if _, err := uuid.Parse(code); err == nil {
+			id = code
+		}
+	}
+	if id != "" {
+		it, err := r.FindItemByID(ctx, id)
+		if err == nil {
+			return it, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	// 不知道扫到的是哪种码，两种归一化都试
+	var ident models.ItemIdentifier
+	err := db.Where("value IN ?", []string{
+		models.NormalizeIdentifier(models.IdentKindBarcode, code),
+		models.NormalizeIdentifier(models.IdentKindNFC, code),
+	}).First(&ident).Error
+	if err == nil {
+		return r.FindItemByID(ctx, ident.ItemID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var it models.Item
+	err = db.Where("LOWER(serial) = LOWER(?)", code).First(&it).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnknownIdentifier
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &it, nil
+}
+
+func (r *Repo) AddItemIdentifier(ctx context.Context, ident *models.ItemIdentifier) error {
+	if !models.IsIdentKind(ident.Kind) {
+		return ErrInvalidIdentifier
+	}
+	ident.Value = models.NormalizeIdentifier(ident.Kind, ident.Value)
+	if ident.Value == "" {
+		return ErrInvalidIdentifier
+	}
+	if _, err := r.FindItemByID(ctx, ident.ItemID); err != nil {
+		return err
+	}
+	// 不能与别的物品的编号撞车，否则扫码结果有歧义
+	var n int64
+	if err := r.DB.WithContext(ctx).Model(&models.Item{}).
+		Where("LOWER(serial) = LOWER(?) AND id <> ?", ident.Value, ident.ItemID).
+		Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrIdentifierTaken
+	}
+	ident.ID = uuid.NewString()
+	if err := r.DB.WithContext(ctx).Create(ident).Error; err != nil {
+		if isUniqueViolation(err) {
+			return ErrIdentifierTaken
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *Repo) ListItemIdentifiers(ctx context.Context, itemID string) ([]models.ItemIdentifier, error) {
+	out := []models.ItemIdentifier{}
+	err := r.DB.WithContext(ctx).
+		Where("item_id = ?", itemID).
+		Order("created_at ASC").
+		Find(&out).Error
+	return out, err
+}
+
+func (r *Repo) DeleteItemIdentifier(ctx context.Context, id string) error {
+	res := r.DB.WithContext(ctx).Delete(&models.ItemIdentifier{}, "id = ?", id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindOpenLoanByItem 当前未归还的借用；没有时返回 nil, nil
+func (r *Repo) FindOpenLoanByItem(ctx context.Context, itemID string) (*models.Loan, error) {
+	var l models.Loan
+	err := r.DB.WithContext(ctx).
+		Where("item_id = ? AND returned_at IS NULL", itemID).
+		First(&l).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
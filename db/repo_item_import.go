@@ -0,0 +1,258 @@
+// db/repo_item_import.go
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 导入行结果
+const (
+	ImportWillCreate = "will_create" // 试运行：校验通过
+	ImportCreated    = "created"
+	ImportSkipped    = "skipped" // 校验失败或编号已存在
+)
+
+type ItemImportRow struct {
+	Line       int // CSV 中的行号（含表头，从 1 开始）
+	Serial     string
+	Name       string
+	Status     string
+	Category   string // 分类 id、完整路径（"电动工具 / 电钻"）或唯一的分类名
+	Attributes map[string]string
+}
+
+type ItemImportResult struct {
+	Line   int      `json:"line"`
+	Serial string   `json:"serial"`
+	Result string   `json:"result"`
+	ItemID string   `json:"itemId,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+type ItemImportReport struct {
+	DryRun  bool               `json:"dryRun"`
+	Total   int                `json:"total"`
+	Created int                `json:"created"`
+	Skipped int                `json:"skipped"`
+	Rows    []ItemImportResult `json:"rows"`
+}
+
+// importCategoryResolver 按 id / 路径 / 唯一名称找分类（不区分大小写）
+func (r *Repo) importCategoryResolver(ctx context.Context) (func(string) (*string, error), error) {
+	rows, err := r.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byKey := map[string]string{}
+	nameCount := map[string]int{}
+	for _, c := range rows {
+		byKey[c.ID] = c.ID
+		byKey[strings.ToLower(c.Path)] = c.ID
+		nameCount[strings.ToLower(c.Name)]++
+	}
+	for _, c := range rows {
+		if n := strings.ToLower(c.Name); nameCount[n] == 1 {
+			if _, taken := byKey[n]; !taken {
+				byKey[n] = c.ID
+			}
+		}
+	}
+	return func(s string) (*string, error) {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil, nil
+		}
+		key := strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(s, "/", " / ")), " "))
+		if id, ok := byKey[key]; ok {
+			return &id, nil
+		}
+		if id, ok := byKey[s]; ok {
+			return &id, nil
+		}
+		if nameCount[strings.ToLower(s)] > 1 {
+			return nil, fmt.Errorf("category %q is ambiguous, use the full path", s)
+		}
+		return nil, fmt.Errorf("unknown category %q", s)
+	}, nil
+}
+
+// validateImportRows 逐行校验：必填、长度、状态合法、文件内编号重复、库里已有编号、分类及属性 schema；
+// 返回的 items 与 rows 一一对应，校验失败的为 nil
+func (r *Repo) validateImportRows(ctx context.Context, tx *gorm.DB, rows []ItemImportRow) ([]ItemImportResult, []*models.Item, error) {
+	serials := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.Serial != "" {
+			serials = append(serials, row.Serial)
+		}
+	}
+	existing := map[string]bool{}
+	if len(serials) > 0 {
+		var found []string
+		if err := tx.Model(&models.Item{}).Where("serial IN ?", serials).Pluck("serial", &found).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, s := range found {
+			existing[s] = true
+		}
+	}
+	resolveCategory, err := r.importCategoryResolver(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	schemas := map[string][]models.CategoryAttribute{}
+
+	firstLine := map[string]int{}
+	out := make([]ItemImportResult, len(rows))
+	items := make([]*models.Item, len(rows))
+	for i, row := range rows {
+		res := ItemImportResult{Line: row.Line, Serial: row.Serial}
+		if row.Serial == "" {
+			res.Errors = append(res.Errors, "missing serial")
+		}
+		if row.Name == "" {
+			res.Errors = append(res.Errors, "missing name")
+		}
+		// 与 UpdateItem 相同的长度上限，超长的行单独跳过，不让整批在落库时失败
+		if utf8.RuneCountInString(row.Serial) > 120 {
+			res.Errors = append(res.Errors, "serial must be at most 120 characters")
+		}
+		if utf8.RuneCountInString(row.Name) > 200 {
+			res.Errors = append(res.Errors, "name must be at most 200 characters")
+		}
+		if !models.IsItemStatus(row.Status) {
+			res.Errors = append(res.Errors, fmt.Sprintf("invalid status %q", row.Status))
+		}
+		if row.Serial != "" {
+			if l, dup := firstLine[row.Serial]; dup {
+				res.Errors = append(res.Errors, fmt.Sprintf("duplicate serial (same as line %d)", l))
+			} else {
+				firstLine[row.Serial] = row.Line
+			}
+			if existing[row.Serial] {
+				res.Errors = append(res.Errors, "serial already exists")
+			}
+		}
+
+		var attrs models.JSONMap
+		categoryID, err := resolveCategory(row.Category)
+		if err != nil {
+			res.Errors = append(res.Errors, err.Error())
+		} else {
+			key := ""
+			if categoryID != nil {
+				key = *categoryID
+			}
+			schema, ok := schemas[key]
+			if !ok {
+				if schema, err = loadAttributeSchema(tx, categoryID); err != nil {
+					return nil, nil, err
+				}
+				schemas[key] = schema
+			}
+			raw := map[string]any{}
+			for k, v := range row.Attributes {
+				raw[k] = v
+			}
+			if attrs, err = validateAttributes(schema, raw); err != nil {
+				var ae *AttributeError
+				if errors.As(err, &ae) {
+					res.Errors = append(res.Errors, ae.Problems...)
+				} else {
+					return nil, nil, err
+				}
+			}
+		}
+
+		if len(res.Errors) > 0 {
+			res.Result = ImportSkipped
+		} else {
+			res.Result = ImportWillCreate
+			items[i] = &models.Item{
+				Serial:     row.Serial,
+				Name:       row.Name,
+				Status:     row.Status,
+				Version:    1,
+				CategoryID: categoryID,
+				Attributes: attrs,
+			}
+		}
+		out[i] = res
+	}
+	return out, items, nil
+}
+
+// ImportItems 批量导入物品；dryRun 只出报告，否则在一个事务里插入所有通过校验的行
+func (r *Repo) ImportItems(ctx context.Context, rows []ItemImportRow, dryRun bool) (*ItemImportReport, error) {
+	for i := range rows {
+		rows[i].Serial = strings.TrimSpace(rows[i].Serial)
+		rows[i].Name = strings.TrimSpace(rows[i].Name)
+		rows[i].Status = strings.ToLower(strings.TrimSpace(rows[i].Status))
+		if rows[i].Status == "" {
+			rows[i].Status = models.ItemStatusActive
+		}
+	}
+	rep := &ItemImportReport{DryRun: dryRun, Total: len(rows)}
+
+	if dryRun {
+		results, _, err := r.validateImportRows(ctx, r.DB.WithContext(ctx), rows)
+		if err != nil {
+			return nil, err
+		}
+		rep.Rows = results
+		for _, res := range results {
+			if res.Result == ImportSkipped {
+				rep.Skipped++
+			}
+		}
+		return rep, nil
+	}
+
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		results, items, err := r.validateImportRows(ctx, tx, rows)
+		if err != nil {
+			return err
+		}
+		for i, it := range items {
+			if it == nil {
+				continue
+			}
+			it.ID = uuid.NewString()
+			// 与并发创建撞编号时跳过而不是整批失败
+			res := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "serial"}}, DoNothing: true}).Create(it)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				results[i].Result = ImportSkipped
+				results[i].Errors = append(results[i].Errors, "serial already exists")
+				continue
+			}
+			results[i].Result = ImportCreated
+			results[i].ItemID = it.ID
+		}
+		rep.Rows = results
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range rep.Rows {
+		switch res.Result {
+		case ImportCreated:
+			rep.Created++
+		case ImportSkipped:
+			rep.Skipped++
+		}
+	}
+	return rep, nil
+}
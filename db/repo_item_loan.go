@@ -3,9 +3,11 @@ package db
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
+	"Gin_postgres_redis_rent_tool/cart"
 	"Gin_postgres_redis_rent_tool/models"
 
 	"github.com/google/uuid"
@@ -15,7 +17,30 @@ import (
 
 // Items
 func (r *Repo) CreateItem(ctx context.Context, it *models.Item) error {
-	return r.DB.WithContext(ctx).Create(it).Error
+	it.Tags = NormalizeTags(it.Tags)
+	it.Version = 1
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if it.CategoryID != nil {
+			var n int64
+			if err := tx.Model(&models.Category{}).Where("id = ?", *it.CategoryID).Count(&n).Error; err != nil {
+				return err
+			}
+			if n == 0 {
+				return fmt.Errorf("%w: category not found", ErrInvalidCategory)
+			}
+		}
+		schema, err := loadAttributeSchema(tx, it.CategoryID)
+		if err != nil {
+			return err
+		}
+		if it.Attributes, err = validateAttributes(schema, it.Attributes); err != nil {
+			return err
+		}
+		if err := tx.Create(it).Error; err != nil {
+			return err
+		}
+		return replaceItemTags(tx, it.ID, it.Tags)
+	})
 }
 func (r *Repo) FindItemByID(ctx context.Context, id string) (*models.Item, error) {
 	var it models.Item
@@ -24,93 +49,222 @@ func (r *Repo) FindItemByID(ctx context.Context, id string) (*models.Item, error
 	}
 	return &it, nil
 }
-func (r *Repo) ListItems(ctx context.Context) ([]models.Item, error) {
+
+// FindItemsByIDs 按 id 批量取物品，不存在的忽略
+func (r *Repo) FindItemsByIDs(ctx context.Context, ids []string) ([]models.Item, error) {
 	var items []models.Item
-	err := r.DB.WithContext(ctx).Order("created_at DESC").Find(&items).Error
+	if len(ids) == 0 {
+		return items, nil
+	}
+	err := r.DB.WithContext(ctx).Where("id IN ?", ids).Find(&items).Error
 	return items, err
 }
 
+type ItemsQuery struct {
+	CategoryID string // 含子分类
+	Tag        string
+}
+
+func (r *Repo) ListItems(ctx context.Context, q ItemsQuery) ([]models.Item, error) {
+	var items []models.Item
+	tx := r.DB.WithContext(ctx).Table(models.ItemTable + " i").Select("i.*").
+		Where("i.archived_at IS NULL").
+		Order("i.created_at DESC")
+	tx = itemCategoryTagFilter(tx, q.CategoryID, q.Tag)
+	if err := tx.Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, attachItemTags(r.DB.WithContext(ctx), items)
+}
+
+// itemCategoryTagFilter 分类（含子分类）与标签过滤，物品表别名为 i
+func itemCategoryTagFilter(tx *gorm.DB, categoryID, tag string) *gorm.DB {
+	if categoryID != "" {
+		tx = tx.Where("i.category_id IN ("+categorySubtreeSQL+")", categoryID)
+	}
+	if t := strings.ToLower(strings.TrimSpace(tag)); t != "" {
+		tx = tx.Where("EXISTS (SELECT 1 FROM "+models.ItemTagTable+" t WHERE t.item_id = i.id AND t.tag = ?)", t)
+	}
+	return tx
+}
+
 // Loans
 var ErrAlreadyBorrowed = errors.New("item already borrowed")
 
-// 借出：原子操作 = 锁住 item → 占用 in_use → 新建 loan
-func (r *Repo) BorrowItem(ctx context.Context, userID, itemID string, dueAt *time.Time, note string) (*models.Loan, error) {
+// 借出：原子操作 = 锁住 item → 占用 in_use → 新建 loan；cond 不为空时一并记录借出状况
+func (r *Repo) BorrowItem(ctx context.Context, userID, itemID string, dueAt *time.Time, note string, cond *ConditionInput) (*models.Loan, error) {
 	var loan *models.Loan
 	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 1) 锁住该物品
+		// 1) 锁住该物品；不存在、停用或已归档都算不可借
 		var it models.Item
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			First(&it, "id = ? AND status = 'active'", itemID).Error; err != nil {
+			First(&it, "id = ? AND status = 'active' AND archived_at IS NULL", itemID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrItemNotAvailable
+			}
 			return err
 		}
-		// 2) 防并发：若已 in_use 或存在未归还 Loan，则拒绝
-		if it.InUse {
-			return ErrAlreadyBorrowed
-		}
-		var n int64
-		if err := tx.Model(&models.Loan{}).
-			Where("item_id = ? AND returned_at IS NULL", itemID).
-			Count(&n).Error; err != nil {
+		// 需审批的物品不能自助借出，由调用方改为提交借用申请
+		if err := checkApproval(tx, it.ID); err != nil {
 			return err
 		}
-		if n > 0 {
-			return ErrAlreadyBorrowed
+		now := time.Now().UTC()
+		l, err := r.checkoutLocked(tx, &it, userID, now, dueAt, note, nil)
+		if err != nil {
+			return err
 		}
-		// 3) 先占位（UPDATE ... WHERE id=? AND in_use=false 也可）
-		if err := tx.Model(&models.Item{}).
-			Where("id = ? AND in_use = FALSE", it.ID).
-			Update("in_use", true).Error; err != nil {
+		loan = l
+		_, err = recordCondition(tx, l, models.ConditionAtCheckout, cond, userID, now)
+		return err
+	})
+	return loan, err
+}
+
+// BorrowItems 一次借出多件（购物车结账）：按 id 顺序锁住全部物品，逐件走与 BorrowItem 相同的检查；
+// 任一件不可借则整体回滚。各件按自己的借用策略计算到期时间
+func (r *Repo) BorrowItems(ctx context.Context, userID string, itemIDs []string, dueAt *time.Time, note string) ([]models.Loan, error) {
+	var loans []models.Loan
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var items []models.Item
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", itemIDs).
+			Order("id").
+			Find(&items).Error; err != nil {
 			return err
 		}
-		// 4) 新建 Loan
-		now := time.Now().UTC()
-		if dueAt == nil {
-			d := now.Add(48 * time.Hour)
-			dueAt = &d
+		found := map[string]bool{}
+		for _, it := range items {
+			found[it.ID] = true
+			if it.Status != models.ItemStatusActive || it.ArchivedAt != nil {
+				return &BatchItemError{ItemID: it.ID, Serial: it.Serial, Err: ErrItemNotAvailable}
+			}
 		}
-
-		l := &models.Loan{
-			ID:         uuid.NewString(),
-			ItemID:     it.ID,
-			UserID:     userID,
-			BorrowedAt: now,
-			DueAt:      dueAt,
-			Note:       note,
+		for _, id := range itemIDs {
+			if !found[id] {
+				return &BatchItemError{ItemID: id, Err: gorm.ErrRecordNotFound}
+			}
 		}
-		if err := tx.Create(l).Error; err != nil {
-			return err
+		now := time.Now().UTC()
+		loans = make([]models.Loan, 0, len(items))
+		for i := range items {
+			if err := checkApproval(tx, items[i].ID); err != nil {
+				return &BatchItemError{ItemID: items[i].ID, Serial: items[i].Serial, Err: err}
+			}
+			l, err := r.checkoutLocked(tx, &items[i], userID, now, dueAt, note, nil)
+			if err != nil {
+				return &BatchItemError{ItemID: items[i].ID, Serial: items[i].Serial, Err: err}
+			}
+			loans = append(loans, *l)
 		}
-		loan = l
 		return nil
 	})
-	return loan, err
+	if err != nil {
+		return nil, err
+	}
+	return loans, nil
+}
+
+// HeldError 物品在别人的购物车里占用着
+type HeldError struct{ Hold cart.Hold }
+
+func (e *HeldError) Error() string { return cart.ErrHeld.Error() }
+func (e *HeldError) Unwrap() error { return cart.ErrHeld }
+
+// checkCartHold 物品被 userID 以外的人放进购物车占用着则拒绝（调用方须已锁住 item 行）
+func (r *Repo) checkCartHold(tx *gorm.DB, itemID, userID string) error {
+	if r.Cart == nil {
+		return nil
+	}
+	h, err := r.Cart.HeldByOther(tx.Statement.Context, userID, itemID)
+	if err != nil {
+		return err
+	}
+	if h != nil {
+		return &HeldError{Hold: *h}
+	}
+	return nil
 }
 
-// 归还：原子操作 = 完成 loan → 释放 in_use
-func (r *Repo) ReturnLoan(ctx context.Context, loanID string, returnedBy string) (*models.Loan, error) {
+// checkoutLocked 借出的各项检查与落库（调用方须已锁住 item 行）；单件借出、成套借出与批准申请共用
+func (r *Repo) checkoutLocked(tx *gorm.DB, it *models.Item, userID string, now time.Time, dueAt *time.Time, note string, kitCheckoutID *string) (*models.Loan, error) {
+	// 2) 防并发：若已 in_use 或存在未归还 Loan，则拒绝
+	if it.InUse {
+		return nil, ErrAlreadyBorrowed
+	}
+	// 锁住 item 后再查购物车占用，避免检查与借出之间被他人占用后仍借出
+	if err := r.checkCartHold(tx, it.ID, userID); err != nil {
+		return nil, err
+	}
+	var n int64
+	if err := tx.Model(&models.Loan{}).
+		Where("item_id = ? AND returned_at IS NULL", it.ID).
+		Count(&n).Error; err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		return nil, ErrAlreadyBorrowed
+	}
+	// 校准/保养已过期的物品禁止借出
+	if err := checkMaintenanceBlock(tx, it.ID); err != nil {
+		return nil, err
+	}
+	// 2.1) 借用策略：数量/时段/禁借日，并得出最终到期时间
+	dueAt, err := evaluateLoanPolicy(tx, it.ID, userID, now, dueAt)
+	if err != nil {
+		return nil, err
+	}
+	// 2.2) 排队认领：若物品正由排队中的他人认领，则拒绝
+	claim, err := checkWaitlistClaim(tx, it.ID, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	// 3) 先占位（UPDATE ... WHERE id=? AND in_use=false 也可）；借出期间不在任何位置
+	if err := tx.Model(&models.Item{}).
+		Where("id = ? AND in_use = FALSE", it.ID).
+		Updates(map[string]any{"in_use": true, "current_location_id": nil}).Error; err != nil {
+		return nil, err
+	}
+	// 4) 不得占用他人已确认的预约时段，然后新建 Loan
+	if err := checkReservationConflict(tx, it.ID, userID, now, dueAt); err != nil {
+		return nil, err
+	}
+
+	l := &models.Loan{
+		ID:            uuid.NewString(),
+		ItemID:        it.ID,
+		UserID:        userID,
+		BorrowedAt:    now,
+		DueAt:         dueAt,
+		Note:          note,
+		KitCheckoutID: kitCheckoutID,
+	}
+	if err := snapshotPricing(tx, l); err != nil {
+		return nil, err
+	}
+	if err := tx.Create(l).Error; err != nil {
+		return nil, err
+	}
+	if err := fulfillWaitlistClaim(tx, claim, l.ID, now); err != nil {
+		return nil, err
+	}
+	it.InUse = true
+	return l, nil
+}
+
+// 归还：原子操作 = 完成 loan → 释放 in_use；locationID 为放回的位置，为空则回到归位处
+// cond 不为空时一并记录归还状况，有损坏或缺配件的物品转入 maintenance
+func (r *Repo) ReturnLoan(ctx context.Context, loanID string, returnedBy string, locationID *string, cond *ConditionInput) (*models.Loan, error) {
 	var l models.Loan
 	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 			First(&l, "id = ?", loanID).Error; err != nil {
 			return err
 		}
-		// 幂等：已归还直接返回
-		if l.ReturnedAt != nil {
-			return nil
+		if cond != nil && l.ReturnedAt != nil {
+			return fmt.Errorf("%w: loan is already returned", ErrInvalidCondition)
 		}
 		now := time.Now().UTC()
-		l.ReturnedAt = &now
-		l.ReturnedBy = &returnedBy
-		if err := tx.Save(&l).Error; err != nil {
-			return err
-		}
-		// 释放占用
-		if err := tx.Model(&models.Item{}).
-			Where("id = ?", l.ItemID).
-			Update("in_use", false).Error; err != nil {
-			return err
-		}
-		return nil
+		return returnLocked(tx, &l, returnedBy, locationID, cond, "", now)
 	})
 	if err != nil {
 		return nil, err
@@ -118,6 +272,52 @@ func (r *Repo) ReturnLoan(ctx context.Context, loanID string, returnedBy string)
 	return &l, nil
 }
 
+// returnLocked 归还一条借用（调用方须已锁住 loan 行）；已归还的直接返回（幂等）
+// cond 不为空时记录归还状况，记录人为 actorID，为空则记在归还人名下
+func returnLocked(tx *gorm.DB, l *models.Loan, returnedBy string, locationID *string, cond *ConditionInput, actorID string, now time.Time) error {
+	if l.ReturnedAt != nil {
+		return nil
+	}
+	loc, err := returnedLocation(tx, l.ItemID, locationID)
+	if err != nil {
+		return err
+	}
+	l.ReturnedAt = &now
+	l.ReturnedBy = &returnedBy
+	l.ReturnLocationID = loc
+	l.Outcome = models.LoanOutcomeReturned
+	if err := tx.Save(l).Error; err != nil {
+		return err
+	}
+	// 释放占用，记录当前位置
+	if err := tx.Model(&models.Item{}).
+		Where("id = ?", l.ItemID).
+		Updates(map[string]any{"in_use": false, "current_location_id": loc}).Error; err != nil {
+		return err
+	}
+	if err := cancelPendingHandovers(tx, l.ID, now); err != nil {
+		return err
+	}
+	if err := chargeClosedLoan(tx, l, now); err != nil {
+		return err
+	}
+	// 成套借出的最后一件归还后，整套视为已归还
+	if l.KitCheckoutID != nil {
+		if err := closeKitCheckoutIfDone(tx, *l.KitCheckoutID, returnedBy, now); err != nil {
+			return err
+		}
+	}
+	// 状况先于排队：转入 maintenance 的物品不发认领
+	if actorID == "" {
+		actorID = returnedBy
+	}
+	if _, err := recordCondition(tx, l, models.ConditionAtReturn, cond, actorID, now); err != nil {
+		return err
+	}
+	// 排队队首获得限时认领
+	return advanceWaitlist(tx, l.ItemID, now)
+}
+
 func (r *Repo) ListLoans(ctx context.Context, userID, itemID, status string) ([]models.Loan, error) {
 	q := r.DB.WithContext(ctx).Model(&models.Loan{}).Order("borrowed_at DESC")
 	if userID != "" {
@@ -130,6 +330,9 @@ func (r *Repo) ListLoans(ctx context.Context, userID, itemID, status string) ([]
 		q = q.Where("returned_at IS NULL")
 	} else if status == "returned" {
 		q = q.Where("returned_at IS NOT NULL")
+	} else if status != "" {
+		// 按结束方式：returned 以外的 handed_over / lost / stolen / destroyed
+		q = q.Where("outcome = ?", status)
 	}
 	var ls []models.Loan
 	if err := q.Find(&ls).Error; err != nil {
@@ -206,9 +409,9 @@ func (r *Repo) ListUsersWithOpenLoans(ctx context.Context, q string, page, size
 
 	// 1) 分页拿这一页用户
 	tx := r.DB.WithContext(ctx).Model(&models.User{})
-	if s := strings.TrimSpace(q); s != "" {
-		pat := "%" + strings.ToLower(s) + "%"
-		tx = tx.Where("LOWER(username) LIKE ? OR LOWER(display_name) LIKE ?", pat, pat)
+	search := newTextSearch(q)
+	if search != nil {
+		tx = search.where(tx, userSearchCols(""))
 	}
 
 	var total int64
@@ -216,9 +419,14 @@ func (r *Repo) ListUsersWithOpenLoans(ctx context.Context, q string, page, size
 		return ListUsersWithOpenLoansResult{}, err
 	}
 
+	// 有搜索词时按相关度排序
+	if search != nil {
+		tx = search.order(tx, userSearchCols(""), "created_at DESC")
+	} else {
+		tx = tx.Order("created_at DESC")
+	}
 	var users []models.User
 	if err := tx.
-		Order("created_at DESC").
 		Offset((page - 1) * size).
 		Limit(size).
 		Find(&users).Error; err != nil {
@@ -0,0 +1,119 @@
+// db/repo_item_status.go
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUnknownItemStatus = errors.New("unknown item status")
+	ErrInvalidTransition = errors.New("status transition not allowed")
+	ErrItemHasOpenLoan   = errors.New("item has an open loan")
+)
+
+type ChangeItemStatusInput struct {
+	ItemID  string
+	To      string
+	Reason  string
+	ActorID string
+}
+
+// clipRunes 超过 n 个字符时截断并以 "..." 结尾，按字符截，不会切坏多字节字符
+func clipRunes(s string, n int) string {
+	rs := []rune(s)
+	if len(rs) <= n {
+		return s
+	}
+	return string(rs[:n-3]) + "..."
+}
+
+// setItemStatus 在事务内改状态并写历史（调用方须已锁住 item 行）
+func setItemStatus(tx *gorm.DB, it *models.Item, to, reason, actorID string, now time.Time) error {
+	if err := tx.Model(&models.Item{}).
+		Where("id = ?", it.ID).
+		Updates(map[string]any{"status": to, "updated_at": now, "version": gorm.Expr("version + 1")}).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(&models.ItemStatusChange{
+		ItemID:     it.ID,
+		FromStatus: it.Status,
+		ToStatus:   to,
+		Reason:     reason,
+		ActorID:    actorID,
+	}).Error; err != nil {
+		return err
+	}
+	it.Status = to
+	it.UpdatedAt = now
+	it.Version++
+	return nil
+}
+
+// ChangeItemStatus 按状态机迁移；报废前必须无未归还借用，报废时取消其有效预约与排队；
+// 回到 active 时推进排队
+func (r *Repo) ChangeItemStatus(ctx context.Context, in ChangeItemStatusInput) (*models.Item, error) {
+	if !models.IsItemStatus(in.To) {
+		return nil, ErrUnknownItemStatus
+	}
+	var it models.Item
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&it, "id = ?", in.ItemID).Error; err != nil {
+			return err
+		}
+		if !models.CanTransition(it.Status, in.To) {
+			return fmt.Errorf("%w: %s → %s", ErrInvalidTransition, it.Status, in.To)
+		}
+		now := time.Now().UTC()
+
+		if in.To == models.ItemStatusRetired {
+			open, err := hasOpenLoan(tx, &it)
+			if err != nil {
+				return err
+			}
+			if open {
+				return ErrItemHasOpenLoan
+			}
+			if err := cancelItemBookings(tx, it.ID, in.ActorID, now); err != nil {
+				return err
+			}
+		}
+
+		if err := setItemStatus(tx, &it, in.To, in.Reason, in.ActorID, now); err != nil {
+			return err
+		}
+		if in.To == models.ItemStatusActive {
+			return advanceWaitlist(tx, it.ID, now)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &it, nil
+}
+
+type ItemStatusChangeRow struct {
+	models.ItemStatusChange
+	ActorUsername string `json:"actorUsername"`
+}
+
+func (r *Repo) ListItemStatusHistory(ctx context.Context, itemID string) ([]ItemStatusChangeRow, error) {
+	var rows []ItemStatusChangeRow
+	err := r.DB.WithContext(ctx).
+		Table(models.ItemStatusChangeTable+" h").
+		Select("h.*, u.username AS actor_username").
+		Joins("LEFT JOIN lsb_users u ON u.id = h.actor_id").
+		Where("h.item_id = ?", itemID).
+		Order("h.created_at DESC").
+		Scan(&rows).Error
+	return rows, err
+}
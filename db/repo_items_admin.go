@@ -15,11 +15,24 @@ import (
 
 type AdminItemRow struct {
 	// Item fields
-	ID        string    `json:"id"`
-	Serial    string    `json:"serial"`
-	Name      string    `json:"name"`
-	Status    string    `json:"status"`
-	InUse     bool      `json:"inUse"`
+	ID     string `json:"id"`
+	Serial string `json:"serial"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	InUse  bool   `json:"inUse"`
+
+	Version    int64      `json:"version"`
+	ArchivedAt *time.Time `json:"archivedAt,omitempty"`
+
+	CategoryID   *string        `json:"categoryId,omitempty"`
+	CategoryName *string        `json:"categoryName,omitempty"`
+	Attributes   models.JSONMap `json:"attributes"`
+
+	HomeLocationID      *string `json:"homeLocationId,omitempty"`
+	HomeLocationName    *string `json:"homeLocationName,omitempty"`
+	CurrentLocationID   *string `json:"currentLocationId,omitempty"`
+	CurrentLocationName *string `json:"currentLocationName,omitempty"`
+
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 
@@ -31,13 +44,26 @@ type AdminItemRow struct {
 	BorrowedAt          *time.Time `json:"borrowedAt,omitempty"`
 	DueAt               *time.Time `json:"dueAt,omitempty"`
 	Overdue             bool       `json:"overdue"` // 由 SQL 计算
+
+	// 购物车占用（存在 Redis，由 controller 填充）
+	HeldBy    *string    `gorm:"-" json:"heldBy,omitempty"`
+	HeldUntil *time.Time `gorm:"-" json:"heldUntil,omitempty"`
 }
 
 type AdminItemsQuery struct {
-	Q      string // 模糊搜索：serial/name
-	Status string // "", "open", "available", "overdue", "inactive"
-	Page   int
-	Size   int
+	Q          string // 搜索 serial/name，按相关度排序
+	Status     string // "", "open", "available", "overdue", "inactive"
+	ItemStatus string // 生命周期状态："", "active", "maintenance", "retired", "lost", "stolen", "destroyed"
+	CategoryID string // 含子分类
+	Tag        string
+	Archived   string             // "" 不含已归档，"only" 只看已归档，"all" 全部
+	LocationID string             // 含下级位置
+	AtHome     bool               // true 按归位处过滤，否则按当前位置
+	Attrs      map[string]string  // 属性等值过滤（按文本比较）
+	AttrMin    map[string]float64 // 数值属性下限（含）
+	AttrMax    map[string]float64 // 数值属性上限（含）
+	Page       int
+	Size       int
 }
 
 type PagedAdminItems struct {
@@ -45,17 +71,31 @@ type PagedAdminItems struct {
 	Items []AdminItemRow `json:"items"`
 }
 
-func (r *Repo) ListItemsWithCurrentLoan(ctx context.Context, q AdminItemsQuery) (*PagedAdminItems, error) {
-	if q.Page <= 0 {
-		q.Page = 1
-	}
-	if q.Size <= 0 || q.Size > 200 {
-		q.Size = 20
-	}
-	offset := (q.Page - 1) * q.Size
-
-	db := r.DB.WithContext(ctx)
+// adminItemColumns AdminItemRow 的列；当前借用别名 ol，其余表由 adminItemJoins 连接
+const adminItemColumns = `
+	i.id, i.serial, i.name, i.status, i.in_use, i.version, i.archived_at, i.created_at, i.updated_at,
+	i.category_id, cat.name AS category_name, i.attributes,
+	i.home_location_id, hl.name AS home_location_name,
+	i.current_location_id, cl.name AS current_location_name,
+	ol.id        AS loan_id,
+	ol.user_id   AS borrower_id,
+	ol.borrowed_at,
+	ol.due_at,
+	u.username   AS borrower_username,
+	u.display_name AS borrower_display_name,
+	CASE WHEN ol.due_at IS NOT NULL AND ol.due_at < NOW() THEN TRUE ELSE FALSE END AS overdue
+`
+
+func adminItemJoins(qry *gorm.DB) *gorm.DB {
+	return qry.
+		Joins("LEFT JOIN lsb_users u ON u.id = ol.user_id").
+		Joins("LEFT JOIN " + models.CategoryTable + " cat ON cat.id = i.category_id").
+		Joins("LEFT JOIN " + models.LocationTable + " hl ON hl.id = i.home_location_id").
+		Joins("LEFT JOIN " + models.LocationTable + " cl ON cl.id = i.current_location_id")
+}
 
+// adminItemsQuery 物品 + 当前借用的统一视图及过滤条件（列表与导出共用）
+func adminItemsQuery(db *gorm.DB, q AdminItemsQuery) *gorm.DB {
 	// 子查询：每件物品“当前未归还”的最新一条 Loan
 	sub := db.
 		Table(models.LoanTable + " l").
@@ -69,29 +109,20 @@ func (r *Repo) ListItemsWithCurrentLoan(ctx context.Context, q AdminItemsQuery)
 	// 主查询
 	qry := db.
 		Table(models.ItemTable+" i").
-		Select(`
-			i.id, i.serial, i.name, i.status, i.in_use, i.created_at, i.updated_at,
-			ol.id        AS loan_id,
-			ol.user_id   AS borrower_id,
-			ol.borrowed_at,
-			ol.due_at,
-			u.username   AS borrower_username,
-			u.display_name AS borrower_display_name,
-			CASE WHEN ol.due_at IS NOT NULL AND ol.due_at < NOW() THEN TRUE ELSE FALSE END AS overdue
-		`).
-		Joins("LEFT JOIN (?) AS ol ON ol.item_id = i.id", sub).
-		Joins("LEFT JOIN lsb_users u ON u.id = ol.user_id")
+		Select(adminItemColumns).
+		Joins("LEFT JOIN (?) AS ol ON ol.item_id = i.id", sub)
+	qry = adminItemJoins(qry)
 
 	// 过滤
-	if s := strings.TrimSpace(q.Q); s != "" {
-		pat := "%" + strings.ToLower(s) + "%"
-		qry = qry.Where("LOWER(i.serial) LIKE ? OR LOWER(i.name) LIKE ?", pat, pat)
+	if s := newTextSearch(q.Q); s != nil {
+		qry = s.where(qry, itemSearchCols("i."))
 	}
 	switch q.Status {
 	case "open":
 		qry = qry.Where("i.in_use = TRUE")
 	case "available":
-		qry = qry.Where("i.in_use = FALSE")
+		// 丢失 / 报废等已关闭借用的物品 in_use 为 false，但不算可借
+		qry = qry.Where("i.in_use = FALSE AND i.status = 'active' AND i.archived_at IS NULL")
 	case "overdue":
 		qry = qry.Where("ol.due_at IS NOT NULL AND ol.due_at < NOW()")
 	case "inactive":
@@ -99,6 +130,69 @@ func (r *Repo) ListItemsWithCurrentLoan(ctx context.Context, q AdminItemsQuery)
 	default:
 		// all
 	}
+	if q.ItemStatus != "" {
+		qry = qry.Where("i.status = ?", q.ItemStatus)
+	}
+	switch q.Archived {
+	case "only":
+		qry = qry.Where("i.archived_at IS NOT NULL")
+	case "all":
+	default:
+		qry = qry.Where("i.archived_at IS NULL")
+	}
+	if q.LocationID != "" {
+		col := "i.current_location_id"
+		if q.AtHome {
+			col = "i.home_location_id"
+		}
+		qry = qry.Where(col+" IN ("+locationSubtreeSQL+")", q.LocationID)
+	}
+	qry = itemAttributeFilter(qry, q)
+	return itemCategoryTagFilter(qry, q.CategoryID, q.Tag)
+}
+
+// adminItemsOrder 有搜索词时按相关度，否则按创建时间倒序
+func adminItemsOrder(qry *gorm.DB, q AdminItemsQuery) *gorm.DB {
+	if s := newTextSearch(q.Q); s != nil {
+		return s.order(qry, itemSearchCols("i."), "i.created_at DESC")
+	}
+	return qry.Order("i.created_at DESC")
+}
+
+// 数值属性：非数字的值（旧数据）视为 NULL，不参与范围比较
+const attrNumericExpr = "CASE WHEN jsonb_typeof(i.attributes->?) = 'number' THEN (i.attributes->>?)::numeric END"
+
+// itemAttributeFilter 键名先经 attrNameRe 校验，值一律走参数绑定
+func itemAttributeFilter(qry *gorm.DB, q AdminItemsQuery) *gorm.DB {
+	for k, v := range q.Attrs {
+		if attrNameRe.MatchString(k) {
+			qry = qry.Where("LOWER(i.attributes->>?) = LOWER(?)", k, v)
+		}
+	}
+	for k, v := range q.AttrMin {
+		if attrNameRe.MatchString(k) {
+			qry = qry.Where(attrNumericExpr+" >= ?", k, k, v)
+		}
+	}
+	for k, v := range q.AttrMax {
+		if attrNameRe.MatchString(k) {
+			qry = qry.Where(attrNumericExpr+" <= ?", k, k, v)
+		}
+	}
+	return qry
+}
+
+func (r *Repo) ListItemsWithCurrentLoan(ctx context.Context, q AdminItemsQuery) (*PagedAdminItems, error) {
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.Size <= 0 || q.Size > 200 {
+		q.Size = 20
+	}
+	offset := (q.Page - 1) * q.Size
+
+	db := r.DB.WithContext(ctx)
+	qry := adminItemsQuery(db, q)
 
 	// 统计总数（对 items 计数即可）
 	var total int64
@@ -109,7 +203,7 @@ func (r *Repo) ListItemsWithCurrentLoan(ctx context.Context, q AdminItemsQuery)
 	}
 
 	// 排序+分页
-	qry = qry.Order("i.created_at DESC").Offset(offset).Limit(q.Size)
+	qry = adminItemsOrder(qry, q).Offset(offset).Limit(q.Size)
 
 	var rows []AdminItemRow
 	if err := qry.Scan(&rows).Error; err != nil {
@@ -124,6 +218,9 @@ type CreateAdminLoanInput struct {
 	UserID string
 	DueAt  *time.Time // optional
 	Note   string     // optional
+
+	Condition *ConditionInput // 可选：借出时的状况
+	ActorID   string          // 记录状况的管理员；为空则记在借用人名下
 }
 
 func (r *Repo) CreateAdminLoan(ctx context.Context, in CreateAdminLoanInput) (*AdminItemRow, error) {
@@ -148,7 +245,7 @@ func (r *Repo) CreateAdminLoan(ctx context.Context, in CreateAdminLoanInput) (*A
 	}
 
 	// 2) 业务校验（按你的规则可自行调整）
-	if it.Status != "active" {
+	if it.Status != "active" || it.ArchivedAt != nil {
 		tx.Rollback()
 		return nil, errors.New("item is not active")
 	}
@@ -156,6 +253,35 @@ func (r *Repo) CreateAdminLoan(ctx context.Context, in CreateAdminLoanInput) (*A
 		tx.Rollback()
 		return nil, errors.New("item is already in use")
 	}
+	if err := r.checkCartHold(tx, it.ID, in.UserID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := checkMaintenanceBlock(tx, it.ID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 2.1) 借用策略：数量/时段/禁借日，DueAt 为空时按默认借期
+	now := time.Now().UTC()
+	dueAt, err := evaluateLoanPolicy(tx, it.ID, in.UserID, now, in.DueAt)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 2.2) 不得占用他人已确认的预约时段
+	if err := checkReservationConflict(tx, it.ID, in.UserID, now, dueAt); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 2.3) 排队认领：物品正由排队中的他人认领时拒绝
+	claim, err := checkWaitlistClaim(tx, it.ID, in.UserID, now)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
 	// 3) 创建借用记录（依赖唯一部分索引防止并发重复打开）
 	loan := models.Loan{
@@ -163,21 +289,38 @@ func (r *Repo) CreateAdminLoan(ctx context.Context, in CreateAdminLoanInput) (*A
 		ID:         uuid.NewString(),
 		ItemID:     in.ItemID,
 		UserID:     in.UserID,
-		BorrowedAt: time.Now(),
-		DueAt:      in.DueAt,
+		BorrowedAt: now,
+		DueAt:      dueAt,
 		Note:       in.Note,
 	}
+	if err := snapshotPricing(tx, &loan); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 	if err := tx.Create(&loan).Error; err != nil {
 		tx.Rollback()
 		return nil, err
 	}
+	if err := fulfillWaitlistClaim(tx, claim, loan.ID, now); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	actorID := in.ActorID
+	if actorID == "" {
+		actorID = in.UserID
+	}
+	if _, err := recordCondition(tx, &loan, models.ConditionAtCheckout, in.Condition, actorID, now); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
 	// 4) 标记物品为 in_use = true
 	if err := tx.Model(&models.Item{}).
 		Where("id = ?", in.ItemID).
 		Updates(map[string]any{
-			"in_use":     true,
-			"updated_at": time.Now(),
+			"in_use":              true,
+			"current_location_id": nil,
+			"updated_at":          time.Now(),
 		}).Error; err != nil {
 		tx.Rollback()
 		return nil, err
@@ -187,18 +330,9 @@ func (r *Repo) CreateAdminLoan(ctx context.Context, in CreateAdminLoanInput) (*A
 	var row AdminItemRow
 	if err := tx.
 		Table(models.ItemTable+" i").
-		Select(`
-			i.id, i.serial, i.name, i.status, i.in_use, i.created_at, i.updated_at,
-			ol.id        AS loan_id,
-			ol.user_id   AS borrower_id,
-			ol.borrowed_at,
-			ol.due_at,
-			u.username   AS borrower_username,
-			u.display_name AS borrower_display_name,
-			CASE WHEN ol.due_at IS NOT NULL AND ol.due_at < NOW() THEN TRUE ELSE FALSE END AS overdue
-		`).
+		Select(adminItemColumns).
 		Joins("LEFT JOIN "+models.LoanTable+" ol ON ol.item_id = i.id AND ol.returned_at IS NULL").
-		Joins("LEFT JOIN lsb_users u ON u.id = ol.user_id").
+		Scopes(adminItemJoins).
 		Where("i.id = ?", in.ItemID).
 		Scan(&row).Error; err != nil {
 		tx.Rollback()
@@ -214,11 +348,15 @@ func (r *Repo) CreateAdminLoan(ctx context.Context, in CreateAdminLoanInput) (*A
 type ReturnAdminLoanInput struct {
 	ItemID           string
 	ReturnedByUserID string
-	Note             string // 可选：归还备注，若提供会合并写入 Loan.Note
+	Note             string  // 可选：归还备注，若提供会合并写入 Loan.Note
+	LocationID       *string // 可选：放回的位置，为空则回到归位处
+
+	Condition *ConditionInput // 可选：归还时的状况，有损坏或缺配件的物品转入 maintenance
+	ActorID   string          // 记录状况的管理员；为空则记在归还人名下
 }
 
-// ReturnAdminLoan sets returned_at/returned_by on the open loan of the item,
-// flips item.in_use = false, and returns a unified AdminItemRow.
+// ReturnAdminLoan closes the open loan of the item via returnLocked (same path as
+// ReturnLoan), merges the admin note, and returns a unified AdminItemRow.
 func (r *Repo) ReturnAdminLoan(ctx context.Context, in ReturnAdminLoanInput) (*AdminItemRow, error) {
 	tx := r.DB.WithContext(ctx).Begin()
 	defer func() {
@@ -227,12 +365,9 @@ func (r *Repo) ReturnAdminLoan(ctx context.Context, in ReturnAdminLoanInput) (*A
 		}
 	}()
 
-	// 1) 锁定 item 行，避免并发
+	// 1) 确认 item 存在
 	var it models.Item
-	if err := tx.
-		Clauses(clause.Locking{Strength: "UPDATE"}).
-		Where("id = ?", in.ItemID).
-		First(&it).Error; err != nil {
+	if err := tx.Select("id").Where("id = ?", in.ItemID).First(&it).Error; err != nil {
 		tx.Rollback()
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("item not found")
@@ -240,7 +375,7 @@ func (r *Repo) ReturnAdminLoan(ctx context.Context, in ReturnAdminLoanInput) (*A
 		return nil, err
 	}
 
-	// 2) 查找该 item 的 open loan（唯一部分索引保证只有一条）
+	// 2) 锁定该 item 的 open loan（唯一部分索引保证只有一条）；与 ReturnLoan 相同只锁 loan
 	var loan models.Loan
 	if err := tx.
 		Clauses(clause.Locking{Strength: "UPDATE"}).
@@ -253,34 +388,13 @@ func (r *Repo) ReturnAdminLoan(ctx context.Context, in ReturnAdminLoanInput) (*A
 		return nil, err
 	}
 
-	now := time.Now()
-
-	// 3) 更新 loan：returned_at / returned_by (+ 合并备注)
-	update := map[string]any{
-		"returned_at": now,
-		"returned_by": in.ReturnedByUserID,
-		"updated_at":  now,
-	}
+	// 3) 合并归还备注，随 returnLocked 一并保存
 	if strings.TrimSpace(in.Note) != "" {
-		// 合并到 Note（简单拼接；如需更复杂策略可自行调整）
-		newNote := strings.TrimSpace(strings.TrimSpace(loan.Note+" ") + in.Note)
-		update["note"] = newNote
-	}
-
-	if err := tx.Model(&models.Loan{}).
-		Where("id = ?", loan.ID).
-		Updates(update).Error; err != nil {
-		tx.Rollback()
-		return nil, err
+		loan.Note = strings.TrimSpace(strings.TrimSpace(loan.Note+" ") + in.Note)
 	}
 
-	// 4) 标记 item 为未借出
-	if err := tx.Model(&models.Item{}).
-		Where("id = ?", in.ItemID).
-		Updates(map[string]any{
-			"in_use":     false,
-			"updated_at": now,
-		}).Error; err != nil {
+	// 4) 归还：释放占用、计费、状况记录、推进排队
+	if err := returnLocked(tx, &loan, in.ReturnedByUserID, in.LocationID, in.Condition, in.ActorID, time.Now().UTC()); err != nil {
 		tx.Rollback()
 		return nil, err
 	}
@@ -289,18 +403,9 @@ func (r *Repo) ReturnAdminLoan(ctx context.Context, in ReturnAdminLoanInput) (*A
 	var row AdminItemRow
 	if err := tx.
 		Table(models.ItemTable+" i").
-		Select(`
-			i.id, i.serial, i.name, i.status, i.in_use, i.created_at, i.updated_at,
-			ol.id        AS loan_id,
-			ol.user_id   AS borrower_id,
-			ol.borrowed_at,
-			ol.due_at,
-			u.username   AS borrower_username,
-			u.display_name AS borrower_display_name,
-			CASE WHEN ol.due_at IS NOT NULL AND ol.due_at < NOW() THEN TRUE ELSE FALSE END AS overdue
-		`).
+		Select(adminItemColumns).
 		Joins("LEFT JOIN "+models.LoanTable+" ol ON ol.item_id = i.id AND ol.returned_at IS NULL").
-		Joins("LEFT JOIN lsb_users u ON u.id = ol.user_id").
+		Scopes(adminItemJoins).
 		Where("i.id = ?", in.ItemID).
 		Scan(&row).Error; err != nil {
 		tx.Rollback()
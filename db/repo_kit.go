@@ -0,0 +1,403 @@
+// db/repo_kit.go
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidKit       = errors.New("invalid kit")
+	ErrKitExists        = errors.New("a kit with this name already exists")
+	ErrKitReturned      = errors.New("kit checkout already returned")
+	ErrItemNotAvailable = errors.New("item is not available")
+)
+
+// BatchItemError 一次借出多件（套装 / 购物车）时某一件不可借；Unwrap 为具体原因，便于沿用单件借出的错误映射
+type BatchItemError struct {
+	ItemID string
+	Serial string
+	Err    error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("item %s: %v", e.Serial, e.Err)
+}
+func (e *BatchItemError) Unwrap() error { return e.Err }
+
+type KitMember struct {
+	ItemID string `json:"itemId"`
+	Serial string `json:"serial"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	InUse  bool   `json:"inUse"`
+}
+
+type KitRow struct {
+	models.Kit
+	Items     []KitMember `gorm:"-" json:"items"`
+	Available bool        `json:"available"` // 所有成员均可借（active、未归档、未借出）
+}
+
+type KitCheckoutRow struct {
+	models.KitCheckout
+	KitName string        `json:"kitName"`
+	Loans   []models.Loan `gorm:"-" json:"loans"`
+}
+
+// normalizeKitItems 去重，并确认物品都存在
+func normalizeKitItems(tx *gorm.DB, itemIDs []string) ([]string, error) {
+	seen := map[string]bool{}
+	ids := []string{}
+	for _, id := range itemIDs {
+		id = strings.TrimSpace(id)
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) < 2 {
+		return nil, fmt.Errorf("%w: a kit needs at least two items", ErrInvalidKit)
+	}
+	var n int64
+	if err := tx.Model(&models.Item{}).Where("id IN ?", ids).Count(&n).Error; err != nil {
+		return nil, err
+	}
+	if int(n) != len(ids) {
+		return nil, fmt.Errorf("%w: some items do not exist", ErrInvalidKit)
+	}
+	return ids, nil
+}
+
+func replaceKitItems(tx *gorm.DB, kitID string, itemIDs []string) error {
+	if err := tx.Where("kit_id = ?", kitID).Delete(&models.KitItem{}).Error; err != nil {
+		return err
+	}
+	rows := make([]models.KitItem, 0, len(itemIDs))
+	for _, id := range itemIDs {
+		rows = append(rows, models.KitItem{KitID: kitID, ItemID: id})
+	}
+	return tx.Create(&rows).Error
+}
+
+// ---------- 管理 ----------
+
+func (r *Repo) CreateKit(ctx context.Context, k *models.Kit, itemIDs []string) error {
+	k.Name = strings.TrimSpace(k.Name)
+	if k.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidKit)
+	}
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids, err := normalizeKitItems(tx, itemIDs)
+		if err != nil {
+			return err
+		}
+		k.ID = uuid.NewString()
+		if err := tx.Create(k).Error; err != nil {
+			return err
+		}
+		return replaceKitItems(tx, k.ID, ids)
+	})
+	if isUniqueViolation(err) {
+		return ErrKitExists
+	}
+	return err
+}
+
+// UpdateKit 改名称/说明，并整体替换成员；进行中的借出不受影响
+func (r *Repo) UpdateKit(ctx context.Context, id, name, description string, itemIDs []string) (*models.Kit, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidKit)
+	}
+	var k models.Kit
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&k, "id = ?", id).Error; err != nil {
+			return err
+		}
+		ids, err := normalizeKitItems(tx, itemIDs)
+		if err != nil {
+			return err
+		}
+		k.Name = name
+		k.Description = strings.TrimSpace(description)
+		k.UpdatedAt = time.Now()
+		if err := tx.Save(&k).Error; err != nil {
+			return err
+		}
+		return replaceKitItems(tx, k.ID, ids)
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrKitExists
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+// DeleteKit 只删套装定义；借出记录保留
+func (r *Repo) DeleteKit(ctx context.Context, id string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&models.Kit{}, "id = ?", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("kit_id = ?", id).Delete(&models.KitItem{}).Error
+	})
+}
+
+// ---------- 浏览 ----------
+
+func (r *Repo) ListKits(ctx context.Context) ([]KitRow, error) {
+	var kits []models.Kit
+	if err := r.DB.WithContext(ctx).Order("name").Find(&kits).Error; err != nil {
+		return nil, err
+	}
+	return r.kitRows(ctx, kits)
+}
+
+func (r *Repo) FindKit(ctx context.Context, id string) (*KitRow, error) {
+	var k models.Kit
+	if err := r.DB.WithContext(ctx).First(&k, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	rows, err := r.kitRows(ctx, []models.Kit{k})
+	if err != nil {
+		return nil, err
+	}
+	return &rows[0], nil
+}
+
+func (r *Repo) kitRows(ctx context.Context, kits []models.Kit) ([]KitRow, error) {
+	out := make([]KitRow, len(kits))
+	if len(kits) == 0 {
+		return out, nil
+	}
+	ids := make([]string, len(kits))
+	for i, k := range kits {
+		ids[i] = k.ID
+	}
+	type member struct {
+		KitID      string
+		ArchivedAt *time.Time
+		KitMember
+	}
+	var ms []member
+	if err := r.DB.WithContext(ctx).
+		Table(models.KitItemTable+" ki").
+		Select("ki.kit_id, i.id AS item_id, i.serial, i.name, i.status, i.in_use, i.archived_at").
+		Joins("JOIN "+models.ItemTable+" i ON i.id = ki.item_id").
+		Where("ki.kit_id IN ?", ids).
+		Order("i.serial").
+		Scan(&ms).Error; err != nil {
+		return nil, err
+	}
+	byKit := map[string][]member{}
+	for _, m := range ms {
+		byKit[m.KitID] = append(byKit[m.KitID], m)
+	}
+	for i, k := range kits {
+		row := KitRow{Kit: k, Items: []KitMember{}, Available: len(byKit[k.ID]) > 0}
+		for _, m := range byKit[k.ID] {
+			row.Items = append(row.Items, m.KitMember)
+			if m.InUse || m.Status != models.ItemStatusActive || m.ArchivedAt != nil {
+				row.Available = false
+			}
+		}
+		out[i] = row
+	}
+	return out, nil
+}
+
+// ---------- 借还 ----------
+
+type BorrowKitInput struct {
+	KitID  string
+	UserID string
+	DueAt  *time.Time
+	Note   string
+}
+
+// BorrowKit 整套借出：按 id 顺序锁住全部成员（避免死锁），逐件走与 BorrowItem 相同的检查；
+// 任一件不可借则整体回滚。未指定到期时间时取各成员默认借期中最早的一个，全套同时到期
+func (r *Repo) BorrowKit(ctx context.Context, in BorrowKitInput) (*KitCheckoutRow, error) {
+	var out *KitCheckoutRow
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var k models.Kit
+		if err := tx.First(&k, "id = ?", in.KitID).Error; err != nil {
+			return err
+		}
+		var items []models.Item
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN (?)", tx.Model(&models.KitItem{}).Select("item_id").Where("kit_id = ?", k.ID)).
+			Order("id").
+			Find(&items).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return fmt.Errorf("%w: kit has no items", ErrInvalidKit)
+		}
+		now := time.Now().UTC()
+		for _, it := range items {
+			if it.Status != models.ItemStatusActive || it.ArchivedAt != nil {
+				return &BatchItemError{ItemID: it.ID, Serial: it.Serial, Err: ErrItemNotAvailable}
+			}
+			if err := checkApproval(tx, it.ID); err != nil {
+				return &BatchItemError{ItemID: it.ID, Serial: it.Serial, Err: err}
+			}
+		}
+
+		// 统一到期时间
+		dueAt := in.DueAt
+		if dueAt == nil {
+			for _, it := range items {
+				d, err := evaluateLoanPolicy(tx, it.ID, in.UserID, now, nil)
+				if err != nil {
+					return &BatchItemError{ItemID: it.ID, Serial: it.Serial, Err: err}
+				}
+				if d != nil && (dueAt == nil || d.Before(*dueAt)) {
+					dueAt = d
+				}
+			}
+		}
+
+		kc := &models.KitCheckout{
+			ID:         uuid.NewString(),
+			KitID:      k.ID,
+			UserID:     in.UserID,
+			BorrowedAt: now,
+			DueAt:      dueAt,
+			Note:       in.Note,
+		}
+		if err := tx.Create(kc).Error; err != nil {
+			return err
+		}
+		loans := make([]models.Loan, 0, len(items))
+		for i := range items {
+			l, err := r.checkoutLocked(tx, &items[i], in.UserID, now, dueAt, in.Note, &kc.ID)
+			if err != nil {
+				return &BatchItemError{ItemID: items[i].ID, Serial: items[i].Serial, Err: err}
+			}
+			loans = append(loans, *l)
+		}
+		out = &KitCheckoutRow{KitCheckout: *kc, KitName: k.Name, Loans: loans}
+		return nil
+	})
+	return out, err
+}
+
+// ReturnKit 整套归还：锁住该次借出的全部未归还借用，逐件归还；locationID 为空时各回各的归位处
+func (r *Repo) ReturnKit(ctx context.Context, checkoutID, returnedBy string, locationID *string) (*KitCheckoutRow, error) {
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var kc models.KitCheckout
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&kc, "id = ?", checkoutID).Error; err != nil {
+			return err
+		}
+		if kc.ReturnedAt != nil {
+			return ErrKitReturned
+		}
+		var loans []models.Loan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("kit_checkout_id = ? AND returned_at IS NULL", kc.ID).
+			Order("item_id").
+			Find(&loans).Error; err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		for i := range loans {
+			if err := returnLocked(tx, &loans[i], returnedBy, locationID, nil, "", now); err != nil {
+				return err
+			}
+		}
+		// 成员已被单独还完时也在这里收尾
+		return closeKitCheckoutIfDone(tx, kc.ID, returnedBy, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.FindKitCheckout(ctx, checkoutID)
+}
+
+// closeKitCheckoutIfDone 该次借出已无未归还借用时标记为已归还
+func closeKitCheckoutIfDone(tx *gorm.DB, checkoutID, returnedBy string, now time.Time) error {
+	var open int64
+	if err := tx.Model(&models.Loan{}).
+		Where("kit_checkout_id = ? AND returned_at IS NULL", checkoutID).
+		Count(&open).Error; err != nil {
+		return err
+	}
+	if open > 0 {
+		return nil
+	}
+	return tx.Model(&models.KitCheckout{}).
+		Where("id = ? AND returned_at IS NULL", checkoutID).
+		Updates(map[string]any{"returned_at": now, "returned_by": returnedBy, "updated_at": now}).Error
+}
+
+func (r *Repo) FindKitCheckout(ctx context.Context, id string) (*KitCheckoutRow, error) {
+	rows, err := r.kitCheckoutRows(ctx, r.DB.WithContext(ctx).Where("kc.id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &rows[0], nil
+}
+
+// ListKitCheckouts userID 为空时查全部；openOnly 只看未归还的
+func (r *Repo) ListKitCheckouts(ctx context.Context, userID string, openOnly bool) ([]KitCheckoutRow, error) {
+	tx := r.DB.WithContext(ctx)
+	if userID != "" {
+		tx = tx.Where("kc.user_id = ?", userID)
+	}
+	if openOnly {
+		tx = tx.Where("kc.returned_at IS NULL")
+	}
+	return r.kitCheckoutRows(ctx, tx)
+}
+
+func (r *Repo) kitCheckoutRows(ctx context.Context, filter *gorm.DB) ([]KitCheckoutRow, error) {
+	var rows []KitCheckoutRow
+	if err := filter.
+		Table(models.KitCheckoutTable + " kc").
+		Select("kc.*, k.name AS kit_name").
+		Joins("LEFT JOIN " + models.KitTable + " k ON k.id = kc.kit_id").
+		Order("kc.borrowed_at DESC").
+		Limit(200).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []KitCheckoutRow{}, nil
+	}
+	ids := make([]string, len(rows))
+	for i := range rows {
+		ids[i] = rows[i].ID
+	}
+	var loans []models.Loan
+	if err := r.DB.WithContext(ctx).Where("kit_checkout_id IN ?", ids).Order("borrowed_at").Find(&loans).Error; err != nil {
+		return nil, err
+	}
+	byCheckout := map[string][]models.Loan{}
+	for _, l := range loans {
+		byCheckout[*l.KitCheckoutID] = append(byCheckout[*l.KitCheckoutID], l)
+	}
+	for i := range rows {
+		rows[i].Loans = byCheckout[rows[i].ID]
+	}
+	return rows, nil
+}
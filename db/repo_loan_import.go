@@ -0,0 +1,286 @@
+// db/repo_loan_import.go
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 历史记录里常见的时间写法；无时区的按 UTC
+var importTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "2006/01/02"}
+
+func parseImportTime(s string) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	for _, layout := range importTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			t = t.UTC()
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid time %q", s)
+}
+
+type LoanImportRow struct {
+	Line       int
+	Serial     string
+	Username   string
+	BorrowedAt string
+	DueAt      string
+	ReturnedAt string
+	Note       string
+}
+
+type LoanImportResult struct {
+	Line     int      `json:"line"`
+	Serial   string   `json:"serial"`
+	Username string   `json:"username"`
+	Open     bool     `json:"open"` // 未归还
+	Result   string   `json:"result"`
+	LoanID   string   `json:"loanId,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+type LoanImportReport struct {
+	DryRun  bool               `json:"dryRun"`
+	Total   int                `json:"total"`
+	Created int                `json:"created"`
+	Skipped int                `json:"skipped"`
+	Rows    []LoanImportResult `json:"rows"`
+}
+
+// 校验通过的行
+type loanImportPlan struct {
+	idx  int
+	loan models.Loan
+}
+
+// 借用区间 [borrowed, returned)，未归还视为无穷
+type loanSpan struct {
+	line     int
+	from, to time.Time
+}
+
+func (s loanSpan) overlaps(o loanSpan) bool { return s.from.Before(o.to) && o.from.Before(s.to) }
+
+var farFuture = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// planLoanImport 逐行校验：物品/用户存在、时间合法、同一物品的借用区间互不重叠（含库里已有的）、
+// 每件物品最多一条未归还且物品当前空闲
+func planLoanImport(tx *gorm.DB, rows []LoanImportRow, now time.Time) ([]LoanImportResult, []loanImportPlan, error) {
+	serials := []string{}
+	usernames := []string{}
+	for _, row := range rows {
+		serials = append(serials, row.Serial)
+		usernames = append(usernames, row.Username)
+	}
+
+	items := map[string]models.Item{}
+	var its []models.Item
+	if err := tx.Where("serial IN ?", serials).Find(&its).Error; err != nil {
+		return nil, nil, err
+	}
+	itemIDs := make([]string, 0, len(its))
+	for _, it := range its {
+		items[it.Serial] = it
+		itemIDs = append(itemIDs, it.ID)
+	}
+	users := map[string]string{}
+	var us []models.User
+	if err := tx.Where("username IN ?", usernames).Find(&us).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, u := range us {
+		users[u.Username] = u.ID
+	}
+
+	// 库里已有的借用区间
+	spans := map[string][]loanSpan{}
+	if len(itemIDs) > 0 {
+		var existing []models.Loan
+		if err := tx.Where("item_id IN ?", itemIDs).Find(&existing).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, l := range existing {
+			to := farFuture
+			if l.ReturnedAt != nil {
+				to = *l.ReturnedAt
+			}
+			spans[l.ItemID] = append(spans[l.ItemID], loanSpan{from: l.BorrowedAt, to: to})
+		}
+	}
+
+	results := make([]LoanImportResult, len(rows))
+	var plans []loanImportPlan
+	for i, row := range rows {
+		res := LoanImportResult{Line: row.Line, Serial: row.Serial, Username: row.Username}
+		fail := func(format string, a ...any) { res.Errors = append(res.Errors, fmt.Sprintf(format, a...)) }
+
+		it, okItem := items[row.Serial]
+		if row.Serial == "" {
+			fail("missing serial")
+		} else if !okItem {
+			fail("unknown serial")
+		}
+		userID, okUser := users[row.Username]
+		if row.Username == "" {
+			fail("missing username")
+		} else if !okUser {
+			fail("unknown username")
+		}
+		borrowed, err := parseImportTime(row.BorrowedAt)
+		if err != nil {
+			fail("borrowedAt: %v", err)
+		} else if borrowed == nil {
+			fail("missing borrowedAt")
+		}
+		due, err := parseImportTime(row.DueAt)
+		if err != nil {
+			fail("dueAt: %v", err)
+		}
+		returned, err := parseImportTime(row.ReturnedAt)
+		if err != nil {
+			fail("returnedAt: %v", err)
+		}
+		res.Open = returned == nil
+
+		if borrowed != nil {
+			if borrowed.After(now) {
+				fail("borrowedAt is in the future")
+			}
+			if due != nil && due.Before(*borrowed) {
+				fail("dueAt is before borrowedAt")
+			}
+			if returned != nil && returned.Before(*borrowed) {
+				fail("returnedAt is before borrowedAt")
+			}
+		}
+		if returned != nil && returned.After(now) {
+			fail("returnedAt is in the future")
+		}
+
+		if okItem && borrowed != nil && len(res.Errors) == 0 {
+			if res.Open && (it.InUse || it.Status != models.ItemStatusActive || it.ArchivedAt != nil) {
+				fail("item is in use or not active, cannot import an open loan")
+			}
+			span := loanSpan{line: row.Line, from: *borrowed, to: farFuture}
+			if returned != nil {
+				span.to = *returned
+			}
+			for _, s := range spans[it.ID] {
+				if !span.overlaps(s) {
+					continue
+				}
+				if s.line > 0 {
+					fail("overlaps line %d for the same item", s.line)
+				} else {
+					fail("overlaps an existing loan of this item")
+				}
+				break
+			}
+			if len(res.Errors) == 0 {
+				spans[it.ID] = append(spans[it.ID], span)
+			}
+		}
+
+		if len(res.Errors) > 0 {
+			res.Result = ImportSkipped
+		} else {
+			res.Result = ImportWillCreate
+			l := models.Loan{
+				ItemID:     it.ID,
+				UserID:     userID,
+				BorrowedAt: *borrowed,
+				DueAt:      due,
+				ReturnedAt: returned,
+				Note:       strings.TrimSpace(row.Note),
+			}
+			if returned != nil {
+				l.Outcome = models.LoanOutcomeReturned
+			}
+			plans = append(plans, loanImportPlan{idx: i, loan: l})
+		}
+		results[i] = res
+	}
+	return results, plans, nil
+}
+
+// ImportLoans 导入纸质/表格时代的借用记录；未归还的同时把物品标记为 in_use
+func (r *Repo) ImportLoans(ctx context.Context, rows []LoanImportRow, dryRun bool) (*LoanImportReport, error) {
+	for i := range rows {
+		rows[i].Serial = strings.TrimSpace(rows[i].Serial)
+		rows[i].Username = strings.TrimSpace(rows[i].Username)
+	}
+	rep := &LoanImportReport{DryRun: dryRun, Total: len(rows)}
+	now := time.Now().UTC()
+
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if !dryRun {
+			// 锁住涉及的物品，防止校验后有人借走
+			var locked []models.Item
+			serials := make([]string, 0, len(rows))
+			for _, row := range rows {
+				serials = append(serials, row.Serial)
+			}
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("serial IN ?", serials).
+				Order("id").
+				Find(&locked).Error; err != nil {
+				return err
+			}
+		}
+		results, plans, err := planLoanImport(tx, rows, now)
+		if err != nil {
+			return err
+		}
+		rep.Rows = results
+		if dryRun {
+			return nil
+		}
+		for _, p := range plans {
+			l := p.loan
+			l.ID = uuid.NewString()
+			// 未归还的导入借用归还时才计费，价格按导入时生效的策略
+			if l.ReturnedAt == nil {
+				if err := snapshotPricing(tx, &l); err != nil {
+					return err
+				}
+			}
+			if err := tx.Create(&l).Error; err != nil {
+				return err
+			}
+			if l.ReturnedAt == nil {
+				if err := tx.Model(&models.Item{}).
+					Where("id = ?", l.ItemID).
+					Updates(map[string]any{"in_use": true, "current_location_id": nil}).Error; err != nil {
+					return err
+				}
+			}
+			results[p.idx].Result = ImportCreated
+			results[p.idx].LoanID = l.ID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range rep.Rows {
+		switch res.Result {
+		case ImportCreated:
+			rep.Created++
+		case ImportSkipped:
+			rep.Skipped++
+		}
+	}
+	return rep, nil
+}
@@ -0,0 +1,151 @@
+// db/repo_loan_loss.go
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidLossOutcome = errors.New("outcome must be lost, stolen or destroyed")
+
+type DeclareLossInput struct {
+	LoanID  string
+	Outcome string // lost / stolen / destroyed
+	Note    string
+	ActorID string
+}
+
+// DeclareLoanLoss 宣告借出物品回不来了：关闭借用并记下结束方式，物品转为同名状态（不回到可借），
+// 取消其预约、排队与待审批申请，借用人记为责任人
+func (r *Repo) DeclareLoanLoss(ctx context.Context, in DeclareLossInput) (*models.LoanLoss, error) {
+	if !models.IsLossOutcome(in.Outcome) {
+		return nil, ErrInvalidLossOutcome
+	}
+	var loss *models.LoanLoss
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 与续借相同：先锁 loan，再锁 item
+		var l models.Loan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&l, "id = ?", in.LoanID).Error; err != nil {
+			return err
+		}
+		if l.ReturnedAt != nil {
+			return ErrLoanClosed
+		}
+		var it models.Item
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&it, "id = ?", l.ItemID).Error; err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		note := clipRunes(strings.TrimSpace(in.Note), 255)
+
+		if err := tx.Model(&models.Loan{}).Where("id = ?", l.ID).Updates(map[string]any{
+			"returned_at": now,
+			"returned_by": in.ActorID,
+			"outcome":     in.Outcome,
+			"updated_at":  now,
+		}).Error; err != nil {
+			return err
+		}
+		// 借用已关闭，占用随之解除；物品不在任何位置，状态挡住后续借出
+		if err := tx.Model(&models.Item{}).Where("id = ?", it.ID).
+			Updates(map[string]any{"in_use": false, "current_location_id": nil}).Error; err != nil {
+			return err
+		}
+		reason := "declared " + in.Outcome + " on loan " + l.ID
+		if note != "" {
+			reason += ": " + note
+		}
+		if err := setItemStatus(tx, &it, in.Outcome, clipRunes(reason, 255), in.ActorID, now); err != nil {
+			return err
+		}
+
+		if err := cancelPendingHandovers(tx, l.ID, now); err != nil {
+			return err
+		}
+		// 租金与逾期费算到宣告时为止
+		if err := chargeClosedLoan(tx, &l, now); err != nil {
+			return err
+		}
+		if l.KitCheckoutID != nil {
+			if err := closeKitCheckoutIfDone(tx, *l.KitCheckoutID, in.ActorID, now); err != nil {
+				return err
+			}
+		}
+		if err := cancelItemBookings(tx, it.ID, in.ActorID, now); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.BorrowRequest{}).
+			Where("item_id = ? AND status = ?", it.ID, models.RequestPending).
+			Updates(map[string]any{
+				"status":     models.RequestRejected,
+				"decided_by": in.ActorID,
+				"decided_at": now,
+				"reason":     fmt.Sprintf("item declared %s", in.Outcome),
+				"updated_at": now,
+			}).Error; err != nil {
+			return err
+		}
+
+		loss = &models.LoanLoss{
+			ID:         uuid.NewString(),
+			LoanID:     l.ID,
+			ItemID:     it.ID,
+			UserID:     l.UserID,
+			Outcome:    in.Outcome,
+			Note:       note,
+			DeclaredBy: in.ActorID,
+		}
+		return tx.Create(loss).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loss, nil
+}
+
+type LoanLossRow struct {
+	models.LoanLoss
+	Serial             string    `json:"serial"`
+	Name               string    `json:"name"`
+	Username           string    `json:"username"`
+	DeclaredByUsername *string   `json:"declaredByUsername,omitempty"`
+	BorrowedAt         time.Time `json:"borrowedAt"`
+}
+
+type LoanLossesQuery struct {
+	UserID  string
+	ItemID  string
+	Outcome string
+}
+
+// ListLoanLosses 最近 200 条，新的在前
+func (r *Repo) ListLoanLosses(ctx context.Context, q LoanLossesQuery) ([]LoanLossRow, error) {
+	tx := r.DB.WithContext(ctx).
+		Table(models.LoanLossTable + " ll").
+		Select("ll.*, i.serial, i.name, u.username, du.username AS declared_by_username, l.borrowed_at").
+		Joins("JOIN " + models.ItemTable + " i ON i.id = ll.item_id").
+		Joins("JOIN " + models.LoanTable + " l ON l.id = ll.loan_id").
+		Joins("LEFT JOIN lsb_users u ON u.id = ll.user_id").
+		Joins("LEFT JOIN lsb_users du ON du.id = ll.declared_by")
+	if q.UserID != "" {
+		tx = tx.Where("ll.user_id = ?", q.UserID)
+	}
+	if q.ItemID != "" {
+		tx = tx.Where("ll.item_id = ?", q.ItemID)
+	}
+	if q.Outcome != "" {
+		tx = tx.Where("ll.outcome = ?", q.Outcome)
+	}
+	var rows []LoanLossRow
+	err := tx.Order("ll.created_at DESC").Limit(200).Scan(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,92 @@
+// db/repo_loan_notification.go
+package db
+
+import (
+	"context"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// DueLoanRow 需要提醒的未归还借用（含借用人与物品信息）
+type DueLoanRow struct {
+	LoanID              string    `json:"loanId"`
+	ItemID              string    `json:"itemId"`
+	Serial              string    `json:"serial"`
+	Name                string    `json:"name"`
+	BorrowerID          string    `json:"borrowerId"`
+	BorrowerUsername    string    `json:"borrowerUsername"`
+	BorrowerDisplayName string    `json:"borrowerDisplayName"`
+	BorrowedAt          time.Time `json:"borrowedAt"`
+	DueAt               time.Time `json:"dueAt"`
+}
+
+// ListDueLoans 到期时间落在 [from, to) 且尚未发过 kind 类提醒的未归还借用；
+// 指定 recipients 时按收件人去重：只要还有人没收到就列出（发送失败撤回的、新加入的管理员都会补发）
+func (r *Repo) ListDueLoans(ctx context.Context, kind string, from, to time.Time, recipients ...string) ([]DueLoanRow, error) {
+	var rows []DueLoanRow
+	q := r.DB.WithContext(ctx).
+		Table(models.LoanTable+" l").
+		Select(`
+			l.id AS loan_id, l.item_id, i.serial, i.name,
+			l.user_id AS borrower_id,
+			u.username AS borrower_username,
+			u.display_name AS borrower_display_name,
+			l.borrowed_at, l.due_at
+		`).
+		Joins("JOIN "+models.ItemTable+" i ON i.id = l.item_id").
+		Joins("JOIN lsb_users u ON u.id = l.user_id").
+		Where("l.returned_at IS NULL AND l.due_at >= ? AND l.due_at < ?", from, to)
+	if len(recipients) == 0 {
+		q = q.Where(`NOT EXISTS (
+			SELECT 1 FROM `+models.LoanNotificationTable+` n
+			WHERE n.loan_id = l.id AND n.kind = ? AND n.due_at = l.due_at)`, kind)
+	} else {
+		q = q.Where(`(
+			SELECT COUNT(DISTINCT n.recipient) FROM `+models.LoanNotificationTable+` n
+			WHERE n.loan_id = l.id AND n.kind = ? AND n.due_at = l.due_at AND n.recipient IN ?) < ?`,
+			kind, recipients, len(recipients))
+	}
+	err := q.Order("l.due_at ASC").Scan(&rows).Error
+	return rows, err
+}
+
+// RecordLoanNotification 先占位再发送：返回 false 表示已经发过（或别的副本正在发）
+func (r *Repo) RecordLoanNotification(ctx context.Context, n *models.LoanNotification) (bool, error) {
+	res := r.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(n)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// DeleteLoanNotification 发送失败时撤回占位，下一轮重试
+func (r *Repo) DeleteLoanNotification(ctx context.Context, id uint) error {
+	return r.DB.WithContext(ctx).Delete(&models.LoanNotification{}, id).Error
+}
+
+func (r *Repo) ListLoanNotifications(ctx context.Context, loanID string) ([]models.LoanNotification, error) {
+	var ns []models.LoanNotification
+	q := r.DB.WithContext(ctx).Order("created_at DESC")
+	if loanID != "" {
+		q = q.Where("loan_id = ?", loanID)
+	} else {
+		q = q.Limit(200)
+	}
+	if err := q.Find(&ns).Error; err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+// AdminEmails 数据库中的管理员（用户名即邮箱）
+func (r *Repo) AdminEmails(ctx context.Context) ([]string, error) {
+	var out []string
+	err := r.DB.WithContext(ctx).
+		Model(&models.User{}).
+		Where("is_admin = TRUE").
+		Pluck("LOWER(username)", &out).Error
+	return out, err
+}
@@ -0,0 +1,335 @@
+// db/repo_loan_policy.go
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 未配置任何策略时沿用原来的 48 小时借期
+const defaultLoanHours = 48
+
+// 规则名（PolicyViolation.Rule）
+const (
+	RuleMaxOpenLoans    = "max_open_loans"
+	RuleMaxLoanDuration = "max_loan_duration"
+	RuleDueInPast       = "due_in_past"
+	RuleBorrowingHours  = "borrowing_hours"
+	RuleBlackoutDate    = "blackout_date"
+)
+
+// PolicyViolation 借出被策略拒绝，Rule 指明是哪条规则
+type PolicyViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Limit   any    `json:"limit,omitempty"`
+}
+
+func (v *PolicyViolation) Error() string { return fmt.Sprintf("policy %s: %s", v.Rule, v.Message) }
+
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+// EffectivePolicy 全局 → 分类（由远及近）→ 物品 逐级覆盖后的结果
+type EffectivePolicy struct {
+	MaxOpenLoans     *int    `json:"maxOpenLoans,omitempty"`
+	DefaultLoanHours int     `json:"defaultLoanHours"`
+	MaxLoanHours     *int    `json:"maxLoanHours,omitempty"`
+	BorrowFrom       *string `json:"borrowFrom,omitempty"`
+	BorrowUntil      *string `json:"borrowUntil,omitempty"`
+	Timezone         string  `json:"timezone,omitempty"`
+	RequiresApproval bool    `json:"requiresApproval"`
+	RateCents        int64   `json:"rateCents"`
+	LateFeeCents     int64   `json:"lateFeeCents"`
+	BillingUnit      string  `json:"billingUnit"`
+}
+
+func (e *EffectivePolicy) apply(p models.LoanPolicy) {
+	if p.RequiresApproval != nil {
+		e.RequiresApproval = *p.RequiresApproval
+	}
+	if p.RateCents != nil {
+		e.RateCents = *p.RateCents
+	}
+	if p.LateFeeCents != nil {
+		e.LateFeeCents = *p.LateFeeCents
+	}
+	if p.BillingUnit != nil {
+		e.BillingUnit = *p.BillingUnit
+	}
+	if p.MaxOpenLoans != nil {
+		e.MaxOpenLoans = p.MaxOpenLoans
+	}
+	if p.DefaultLoanHours != nil {
+		e.DefaultLoanHours = *p.DefaultLoanHours
+	}
+	if p.MaxLoanHours != nil {
+		e.MaxLoanHours = p.MaxLoanHours
+	}
+	if p.BorrowFrom != nil && p.BorrowUntil != nil {
+		e.BorrowFrom, e.BorrowUntil = p.BorrowFrom, p.BorrowUntil
+	}
+	if p.Timezone != "" {
+		e.Timezone = p.Timezone
+	}
+}
+
+// 每次借出都现读，管理员改完立即生效
+func loadEffectivePolicy(tx *gorm.DB, itemID string) (EffectivePolicy, error) {
+	eff := EffectivePolicy{DefaultLoanHours: defaultLoanHours, BillingUnit: models.BillingUnitDay}
+	chain, err := itemCategoryChain(tx, itemID)
+	if err != nil {
+		return eff, err
+	}
+	var ps []models.LoanPolicy
+	q := tx.Where("(scope = ? AND scope_id = '') OR (scope = ? AND scope_id = ?)",
+		models.PolicyScopeGlobal, models.PolicyScopeItem, itemID)
+	if len(chain) > 0 {
+		q = q.Or("scope = ? AND scope_id IN ?", models.PolicyScopeCategory, chain)
+	}
+	if err := q.Find(&ps).Error; err != nil {
+		return eff, err
+	}
+	byScope := map[string]models.LoanPolicy{}
+	for _, p := range ps {
+		byScope[p.Scope+":"+p.ScopeID] = p
+	}
+	// 先全局，再从顶级分类到直属分类，最后物品
+	order := []string{models.PolicyScopeGlobal + ":"}
+	for i := len(chain) - 1; i >= 0; i-- {
+		order = append(order, models.PolicyScopeCategory+":"+chain[i])
+	}
+	order = append(order, models.PolicyScopeItem+":"+itemID)
+	for _, k := range order {
+		if p, ok := byScope[k]; ok {
+			eff.apply(p)
+		}
+	}
+	return eff, nil
+}
+
+// parseClock "HH:MM" → 当天分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// evaluateLoanPolicy 借出前按策略校验，返回最终到期时间（dueAt 为空时按默认借期）
+// checkBlackout 当前处于禁借时段则拒绝（借出与续借共用）
+func checkBlackout(tx *gorm.DB, now time.Time) error {
+	var bo models.LoanBlackout
+	err := tx.Where("starts_at <= ? AND ends_at > ?", now, now).Order("ends_at DESC").Take(&bo).Error
+	if err == nil {
+		return &PolicyViolation{
+			Rule:    RuleBlackoutDate,
+			Message: "borrowing is closed until " + bo.EndsAt.Format(time.RFC3339),
+			Limit:   bo.EndsAt,
+		}
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	return err
+}
+
+func evaluateLoanPolicy(tx *gorm.DB, itemID, userID string, now time.Time, dueAt *time.Time) (*time.Time, error) {
+	pol, err := loadEffectivePolicy(tx, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1) 禁借时段
+	if err := checkBlackout(tx, now); err != nil {
+		return nil, err
+	}
+
+	// 2) 允许借出的时段
+	if pol.BorrowFrom != nil && pol.BorrowUntil != nil {
+		loc := time.UTC
+		if pol.Timezone != "" {
+			if l, err := time.LoadLocation(pol.Timezone); err == nil {
+				loc = l
+			}
+		}
+		from, err1 := parseClock(*pol.BorrowFrom)
+		until, err2 := parseClock(*pol.BorrowUntil)
+		if err1 == nil && err2 == nil {
+			local := now.In(loc)
+			m := local.Hour()*60 + local.Minute()
+			inside := m >= from && m < until
+			if from > until { // 跨午夜
+				inside = m >= from || m < until
+			}
+			if !inside {
+				return nil, &PolicyViolation{
+					Rule:    RuleBorrowingHours,
+					Message: fmt.Sprintf("borrowing is only allowed between %s and %s", *pol.BorrowFrom, *pol.BorrowUntil),
+					Limit:   *pol.BorrowFrom + "-" + *pol.BorrowUntil,
+				}
+			}
+		}
+	}
+
+	// 3) 每人同时未归还上限
+	if pol.MaxOpenLoans != nil {
+		var n int64
+		if err := tx.Model(&models.Loan{}).
+			Where("user_id = ? AND returned_at IS NULL", userID).
+			Count(&n).Error; err != nil {
+			return nil, err
+		}
+		if n >= int64(*pol.MaxOpenLoans) {
+			return nil, &PolicyViolation{
+				Rule:    RuleMaxOpenLoans,
+				Message: fmt.Sprintf("at most %d open loan(s) per user", *pol.MaxOpenLoans),
+				Limit:   *pol.MaxOpenLoans,
+			}
+		}
+	}
+
+	// 4) 到期时间
+	if dueAt == nil {
+		d := now.Add(time.Duration(pol.DefaultLoanHours) * time.Hour)
+		dueAt = &d
+	} else if !dueAt.After(now) {
+		return nil, &PolicyViolation{Rule: RuleDueInPast, Message: "dueAt must be in the future"}
+	}
+	if pol.MaxLoanHours != nil && dueAt.Sub(now) > time.Duration(*pol.MaxLoanHours)*time.Hour {
+		return nil, &PolicyViolation{
+			Rule:    RuleMaxLoanDuration,
+			Message: fmt.Sprintf("loan duration cannot exceed %d hour(s)", *pol.MaxLoanHours),
+			Limit:   *pol.MaxLoanHours,
+		}
+	}
+	return dueAt, nil
+}
+
+// ---------- 管理接口 ----------
+
+type LoanPolicyOverview struct {
+	Global     *models.LoanPolicy    `json:"global,omitempty"`
+	Categories []models.LoanPolicy   `json:"categories"`
+	Items      []models.LoanPolicy   `json:"items"`
+	Blackouts  []models.LoanBlackout `json:"blackouts"`
+}
+
+func (r *Repo) GetLoanPolicies(ctx context.Context) (*LoanPolicyOverview, error) {
+	var ps []models.LoanPolicy
+	if err := r.DB.WithContext(ctx).Order("scope, scope_id").Find(&ps).Error; err != nil {
+		return nil, err
+	}
+	out := &LoanPolicyOverview{
+		Categories: []models.LoanPolicy{},
+		Items:      []models.LoanPolicy{},
+		Blackouts:  []models.LoanBlackout{},
+	}
+	for i := range ps {
+		switch ps[i].Scope {
+		case models.PolicyScopeGlobal:
+			out.Global = &ps[i]
+		case models.PolicyScopeCategory:
+			out.Categories = append(out.Categories, ps[i])
+		default:
+			out.Items = append(out.Items, ps[i])
+		}
+	}
+	if err := r.DB.WithContext(ctx).
+		Where("ends_at > NOW()").
+		Order("starts_at ASC").
+		Find(&out.Blackouts).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EffectiveLoanPolicy 某物品当前生效的策略（前端可据此提示默认借期等）
+func (r *Repo) EffectiveLoanPolicy(ctx context.Context, itemID string) (EffectivePolicy, error) {
+	return loadEffectivePolicy(r.DB.WithContext(ctx), itemID)
+}
+
+func validatePolicy(p *models.LoanPolicy) error {
+	for _, v := range []*int{p.MaxOpenLoans, p.DefaultLoanHours, p.MaxLoanHours} {
+		if v != nil && *v <= 0 {
+			return fmt.Errorf("%w: limits must be positive", ErrInvalidPolicy)
+		}
+	}
+	for _, v := range []*int64{p.RateCents, p.LateFeeCents} {
+		if v != nil && *v < 0 {
+			return fmt.Errorf("%w: rates must not be negative", ErrInvalidPolicy)
+		}
+	}
+	if p.BillingUnit != nil && *p.BillingUnit != models.BillingUnitHour && *p.BillingUnit != models.BillingUnitDay {
+		return fmt.Errorf("%w: billingUnit must be hour or day", ErrInvalidPolicy)
+	}
+	if (p.BorrowFrom == nil) != (p.BorrowUntil == nil) {
+		return fmt.Errorf("%w: borrowFrom and borrowUntil must be set together", ErrInvalidPolicy)
+	}
+	if p.BorrowFrom != nil {
+		if _, err := parseClock(*p.BorrowFrom); err != nil {
+			return fmt.Errorf("%w: borrowFrom must be HH:MM", ErrInvalidPolicy)
+		}
+		if _, err := parseClock(*p.BorrowUntil); err != nil {
+			return fmt.Errorf("%w: borrowUntil must be HH:MM", ErrInvalidPolicy)
+		}
+	}
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("%w: unknown timezone", ErrInvalidPolicy)
+		}
+	}
+	return nil
+}
+
+// UpsertLoanPolicy 整条替换某作用域的策略
+func (r *Repo) UpsertLoanPolicy(ctx context.Context, p *models.LoanPolicy) error {
+	if err := validatePolicy(p); err != nil {
+		return err
+	}
+	if p.Scope == models.PolicyScopeGlobal {
+		p.ScopeID = ""
+	} else if p.Scope == models.PolicyScopeItem {
+		if _, err := r.FindItemByID(ctx, p.ScopeID); err != nil {
+			return err
+		}
+	} else if p.Scope == models.PolicyScopeCategory {
+		if err := r.DB.WithContext(ctx).First(&models.Category{}, "id = ?", p.ScopeID).Error; err != nil {
+			return err
+		}
+	} else {
+		return fmt.Errorf("%w: unknown scope", ErrInvalidPolicy)
+	}
+	return r.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "scope"}, {Name: "scope_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"max_open_loans", "default_loan_hours", "max_loan_hours", "requires_approval",
+			"rate_cents", "late_fee_cents", "billing_unit",
+			"borrow_from", "borrow_until", "timezone", "updated_by", "updated_at",
+		}),
+	}).Create(p).Error
+}
+
+func (r *Repo) DeleteLoanPolicy(ctx context.Context, scope, scopeID string) error {
+	return r.DB.WithContext(ctx).
+		Where("scope = ? AND scope_id = ?", scope, scopeID).
+		Delete(&models.LoanPolicy{}).Error
+}
+
+func (r *Repo) CreateLoanBlackout(ctx context.Context, b *models.LoanBlackout) error {
+	if !b.EndsAt.After(b.StartsAt) {
+		return fmt.Errorf("%w: endsAt must be after startsAt", ErrInvalidPolicy)
+	}
+	return r.DB.WithContext(ctx).Create(b).Error
+}
+
+func (r *Repo) DeleteLoanBlackout(ctx context.Context, id uint) error {
+	return r.DB.WithContext(ctx).Delete(&models.LoanBlackout{}, id).Error
+}
@@ -0,0 +1,181 @@
+// db/repo_loan_renewal.go
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrLoanClosed         = errors.New("loan already returned")
+	ErrNotLoanOwner       = errors.New("not the borrower of this loan")
+	ErrRenewalLimit       = errors.New("renewal limit reached")
+	ErrRenewalTooLong     = errors.New("renewal exceeds maximum total loan duration")
+	ErrRenewalWaitlisted  = errors.New("someone is waiting for this item")
+	ErrInvalidRenewalDate = errors.New("new due date must be later than the current one")
+)
+
+// RenewalPolicy 续借规则，来自环境变量：
+// RENEW_MAX_COUNT（默认 2）、RENEW_MAX_TOTAL_HOURS（默认 336 = 14 天）、RENEW_PERIOD_HOURS（默认 48）
+type RenewalPolicy struct {
+	MaxRenewals   int
+	MaxTotal      time.Duration
+	DefaultPeriod time.Duration
+}
+
+func loadRenewalPolicy() RenewalPolicy {
+	getInt := func(k string, d int) int {
+		if v, err := strconv.Atoi(os.Getenv(k)); err == nil && v >= 0 {
+			return v
+		}
+		return d
+	}
+	return RenewalPolicy{
+		MaxRenewals:   getInt("RENEW_MAX_COUNT", 2),
+		MaxTotal:      time.Duration(getInt("RENEW_MAX_TOTAL_HOURS", 14*24)) * time.Hour,
+		DefaultPeriod: time.Duration(getInt("RENEW_PERIOD_HOURS", 48)) * time.Hour,
+	}
+}
+
+type RenewLoanInput struct {
+	LoanID   string
+	ActorID  string
+	NewDueAt *time.Time // 为空时按默认周期从当前到期时间顺延
+	Reason   string
+	ByAdmin  bool // 管理员延期：可操作任意借用，不受次数/总时长/排队限制
+}
+
+// 续借：锁 loan → 锁 item → 校验规则（含借用策略的禁借时段与最长借期）→ 改 due_at + 记一条延期历史
+func (r *Repo) RenewLoan(ctx context.Context, in RenewLoanInput) (*models.Loan, error) {
+	pol := loadRenewalPolicy()
+
+	var l models.Loan
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&l, "id = ?", in.LoanID).Error; err != nil {
+			return err
+		}
+		if l.ReturnedAt != nil {
+			return ErrLoanClosed
+		}
+		if !in.ByAdmin && l.UserID != in.ActorID {
+			return ErrNotLoanOwner
+		}
+		// 与借出/预约/排队串行
+		var it models.Item
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&it, "id = ?", l.ItemID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		base := now
+		if l.DueAt != nil && l.DueAt.After(now) {
+			base = *l.DueAt
+		}
+		newDue := base.Add(pol.DefaultPeriod)
+		if in.NewDueAt != nil {
+			newDue = in.NewDueAt.UTC()
+		}
+		if !newDue.After(base) {
+			return ErrInvalidRenewalDate
+		}
+
+		if !in.ByAdmin {
+			if l.RenewalCount >= pol.MaxRenewals {
+				return ErrRenewalLimit
+			}
+			if newDue.Sub(l.BorrowedAt) > pol.MaxTotal {
+				return ErrRenewalTooLong
+			}
+			// 物品 / 分类的借用策略：禁借时段与最长借期同样约束续借
+			if err := checkBlackout(tx, now); err != nil {
+				return err
+			}
+			lp, err := loadEffectivePolicy(tx, l.ItemID)
+			if err != nil {
+				return err
+			}
+			if lp.MaxLoanHours != nil && newDue.Sub(l.BorrowedAt) > time.Duration(*lp.MaxLoanHours)*time.Hour {
+				return &PolicyViolation{
+					Rule:    RuleMaxLoanDuration,
+					Message: fmt.Sprintf("loan duration cannot exceed %d hour(s)", *lp.MaxLoanHours),
+					Limit:   *lp.MaxLoanHours,
+				}
+			}
+			var waiting int64
+			if err := tx.Model(&models.WaitlistEntry{}).
+				Where("item_id = ? AND status IN ?", l.ItemID,
+					[]string{models.WaitlistWaiting, models.WaitlistOffered}).
+				Count(&waiting).Error; err != nil {
+				return err
+			}
+			if waiting > 0 {
+				return ErrRenewalWaitlisted
+			}
+		}
+		// 延长部分不得压到他人的预约（管理员也不例外）
+		if err := checkReservationConflict(tx, l.ItemID, l.UserID, base, &newDue); err != nil {
+			return err
+		}
+
+		ext := &models.LoanExtension{
+			ID:         uuid.NewString(),
+			LoanID:     l.ID,
+			OldDueAt:   l.DueAt,
+			NewDueAt:   newDue,
+			ExtendedBy: in.ActorID,
+			ByAdmin:    in.ByAdmin,
+			Reason:     in.Reason,
+		}
+		if err := tx.Create(ext).Error; err != nil {
+			return err
+		}
+
+		l.DueAt = &newDue
+		if !in.ByAdmin {
+			l.RenewalCount++
+		}
+		return tx.Model(&models.Loan{}).
+			Where("id = ?", l.ID).
+			Updates(map[string]any{
+				"due_at":        newDue,
+				"renewal_count": l.RenewalCount,
+				"updated_at":    now,
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// 延期历史（按时间正序）
+func (r *Repo) ListLoanExtensions(ctx context.Context, loanID string) ([]models.LoanExtension, error) {
+	var xs []models.LoanExtension
+	if err := r.DB.WithContext(ctx).
+		Where("loan_id = ?", loanID).
+		Order("created_at ASC").
+		Find(&xs).Error; err != nil {
+		return nil, err
+	}
+	return xs, nil
+}
+
+func (r *Repo) FindLoanByID(ctx context.Context, id string) (*models.Loan, error) {
+	var l models.Loan
+	if err := r.DB.WithContext(ctx).First(&l, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
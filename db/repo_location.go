@@ -0,0 +1,260 @@
+// db/repo_location.go
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidLocation  = errors.New("invalid location")
+	ErrLocationExists   = errors.New("a location with this name already exists here")
+	ErrLocationCycle    = errors.New("location cannot be moved under itself")
+	ErrLocationNotEmpty = errors.New("location still has sublocations or items")
+)
+
+// 某位置及其全部下级位置的 id（参数：位置 id）
+const locationSubtreeSQL = `WITH RECURSIVE sub AS (
+	SELECT id FROM ` + models.LocationTable + ` WHERE id = ?
+	UNION
+	SELECT l.id FROM ` + models.LocationTable + ` l JOIN sub ON l.parent_id = sub.id
+) SELECT id FROM sub`
+
+// checkLocationParent 场地必须是顶级，其余必须挂在更高一级的位置下；移动时不能挂到自己的下级
+func checkLocationParent(tx *gorm.DB, id, kind string, parentID *string) error {
+	rank := models.LocationRank(kind)
+	if rank < 0 {
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidLocation, kind)
+	}
+	if parentID == nil {
+		if kind != models.LocationSite {
+			return fmt.Errorf("%w: %s needs a parent", ErrInvalidLocation, kind)
+		}
+		return nil
+	}
+	var p models.Location
+	if err := tx.First(&p, "id = ?", *parentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: parent not found", ErrInvalidLocation)
+		}
+		return err
+	}
+	if models.LocationRank(p.Kind) >= rank {
+		return fmt.Errorf("%w: a %s cannot be placed inside a %s", ErrInvalidLocation, kind, p.Kind)
+	}
+	if id == "" {
+		return nil
+	}
+	var inSubtree int64
+	if err := tx.Raw("SELECT COUNT(*) FROM ("+locationSubtreeSQL+") s WHERE s.id = ?", id, *parentID).
+		Scan(&inSubtree).Error; err != nil {
+		return err
+	}
+	if inSubtree > 0 {
+		return ErrLocationCycle
+	}
+	return nil
+}
+
+// checkLocationExists 物品/归还引用的位置必须存在
+func checkLocationExists(tx *gorm.DB, id *string) error {
+	if id == nil {
+		return nil
+	}
+	var n int64
+	if err := tx.Model(&models.Location{}).Where("id = ?", *id).Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: location not found", ErrInvalidLocation)
+	}
+	return nil
+}
+
+func (r *Repo) CreateLocation(ctx context.Context, l *models.Location) error {
+	l.Name = strings.TrimSpace(l.Name)
+	if l.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidLocation)
+	}
+	if err := checkLocationParent(r.DB.WithContext(ctx), "", l.Kind, l.ParentID); err != nil {
+		return err
+	}
+	l.ID = uuid.NewString()
+	if err := r.DB.WithContext(ctx).Create(l).Error; err != nil {
+		if isUniqueViolation(err) {
+			return ErrLocationExists
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdateLocation 改名、备注或移动；类型不可改
+func (r *Repo) UpdateLocation(ctx context.Context, id, name, note string, parentID *string) (*models.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidLocation)
+	}
+	var l models.Location
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&l, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := checkLocationParent(tx, id, l.Kind, parentID); err != nil {
+			return err
+		}
+		l.Name = name
+		l.Note = strings.TrimSpace(note)
+		l.ParentID = parentID
+		l.UpdatedAt = time.Now()
+		return tx.Save(&l).Error
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrLocationExists
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+// DeleteLocation 只能删空位置：没有下级，也没有物品以它为归位处或当前位置
+func (r *Repo) DeleteLocation(ctx context.Context, id string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var l models.Location
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&l, "id = ?", id).Error; err != nil {
+			return err
+		}
+		var n int64
+		if err := tx.Model(&models.Location{}).Where("parent_id = ?", id).Count(&n).Error; err != nil {
+			return err
+		}
+		if n == 0 {
+			if err := tx.Model(&models.Item{}).
+				Where("home_location_id = ? OR current_location_id = ?", id, id).
+				Count(&n).Error; err != nil {
+				return err
+			}
+		}
+		if n > 0 {
+			return ErrLocationNotEmpty
+		}
+		return tx.Delete(&models.Location{}, "id = ?", id).Error
+	})
+}
+
+type LocationRow struct {
+	models.Location
+	Path      string `json:"path"`      // "总库 / 101 / A架 / 3号位"
+	ItemCount int64  `json:"itemCount"` // 当前放在此处（含下级）的物品数
+}
+
+// ListLocations 扁平列表（按路径排序），前端按 parentId 组树
+func (r *Repo) ListLocations(ctx context.Context) ([]LocationRow, error) {
+	var ls []models.Location
+	if err := r.DB.WithContext(ctx).Find(&ls).Error; err != nil {
+		return nil, err
+	}
+	type cnt struct {
+		LocationID string
+		N          int64
+	}
+	var counts []cnt
+	if err := r.DB.WithContext(ctx).Model(&models.Item{}).
+		Select("current_location_id AS location_id, COUNT(*) AS n").
+		Where("current_location_id IS NOT NULL AND archived_at IS NULL").
+		Group("current_location_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	byID := map[string]models.Location{}
+	for _, l := range ls {
+		byID[l.ID] = l
+	}
+	rows := make([]LocationRow, 0, len(ls))
+	idx := map[string]int{}
+	for _, l := range ls {
+		parts := []string{l.Name}
+		for p, depth := l.ParentID, 0; p != nil && depth < 8; depth++ {
+			pl, ok := byID[*p]
+			if !ok {
+				break
+			}
+			parts = append([]string{pl.Name}, parts...)
+			p = pl.ParentID
+		}
+		rows = append(rows, LocationRow{Location: l, Path: strings.Join(parts, " / ")})
+	}
+	for i, row := range rows {
+		idx[row.ID] = i
+	}
+	// 物品数向上累加到所有上级
+	for _, c := range counts {
+		for cur, depth := c.LocationID, 0; depth < 8; depth++ {
+			i, ok := idx[cur]
+			if !ok {
+				break
+			}
+			rows[i].ItemCount += c.N
+			if rows[i].ParentID == nil {
+				break
+			}
+			cur = *rows[i].ParentID
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Path < rows[j].Path })
+	return rows, nil
+}
+
+// SetItemLocation 设置归位处与当前位置（整体替换，nil 表示清空）
+func (r *Repo) SetItemLocation(ctx context.Context, itemID string, homeID, currentID *string) (*models.Item, error) {
+	var it models.Item
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&it, "id = ?", itemID).Error; err != nil {
+			return err
+		}
+		if err := checkLocationExists(tx, homeID); err != nil {
+			return err
+		}
+		if err := checkLocationExists(tx, currentID); err != nil {
+			return err
+		}
+		if err := bumpItem(tx, itemID, map[string]any{
+			"home_location_id":    homeID,
+			"current_location_id": currentID,
+		}); err != nil {
+			return err
+		}
+		return tx.First(&it, "id = ?", itemID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &it, nil
+}
+
+// returnedLocation 归还时物品放到哪：指定了就用指定的，否则回到归位处
+func returnedLocation(tx *gorm.DB, itemID string, locationID *string) (*string, error) {
+	if locationID != nil {
+		return locationID, checkLocationExists(tx, locationID)
+	}
+	var home []*string
+	if err := tx.Model(&models.Item{}).Where("id = ?", itemID).Pluck("home_location_id", &home).Error; err != nil {
+		return nil, err
+	}
+	if len(home) == 0 {
+		return nil, nil
+	}
+	return home[0], nil
+}
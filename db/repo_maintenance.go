@@ -0,0 +1,311 @@
+// db/repo_maintenance.go
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrMaintenanceDue       = errors.New("item maintenance/calibration has expired")
+	ErrInvalidPlan          = errors.New("invalid maintenance plan")
+	ErrInvalidWorkOrderMove = errors.New("work order status change not allowed")
+)
+
+// 计划自上次完成（或创建）以来的使用小时 = 期间借出时长之和；SQL 中计划表别名为 p
+const planUsageHoursSQL = `COALESCE((
+	SELECT SUM(EXTRACT(EPOCH FROM (COALESCE(l.returned_at, NOW()) - GREATEST(l.borrowed_at, COALESCE(p.last_done_at, p.created_at))))) / 3600
+	FROM ` + models.LoanTable + ` l
+	WHERE l.item_id = p.item_id
+	  AND COALESCE(l.returned_at, NOW()) > COALESCE(p.last_done_at, p.created_at)
+), 0)`
+
+// 计划是否已到期（按日期或按使用小时）
+const planDueSQL = `((p.next_due_at IS NOT NULL AND p.next_due_at <= NOW())
+	OR (p.interval_usage_hours IS NOT NULL AND ` + planUsageHoursSQL + ` >= p.interval_usage_hours))`
+
+// checkMaintenanceBlock 借出前校验：有“到期即禁借”的计划已到期则拒绝；校准过期一律禁借
+func checkMaintenanceBlock(tx *gorm.DB, itemID string) error {
+	var title string
+	err := tx.Table(models.MaintenancePlanTable+" p").
+		Select("p.title").
+		Where("p.item_id = ? AND p.active = TRUE", itemID).
+		Where("p.blocks_borrowing = TRUE OR p.kind = ?", models.PlanKindCalibration).
+		Where(planDueSQL).
+		Limit(1).
+		Scan(&title).Error
+	if err != nil {
+		return err
+	}
+	if title != "" {
+		return fmt.Errorf("%w: %s", ErrMaintenanceDue, title)
+	}
+	return nil
+}
+
+func planNextDue(p *models.MaintenancePlan, from time.Time) *time.Time {
+	if p.IntervalDays == nil {
+		return nil
+	}
+	t := from.AddDate(0, 0, *p.IntervalDays)
+	return &t
+}
+
+func validatePlan(p *models.MaintenancePlan) error {
+	if p.IntervalDays == nil && p.IntervalUsageHours == nil {
+		return fmt.Errorf("%w: intervalDays or intervalUsageHours is required", ErrInvalidPlan)
+	}
+	if (p.IntervalDays != nil && *p.IntervalDays <= 0) || (p.IntervalUsageHours != nil && *p.IntervalUsageHours <= 0) {
+		return fmt.Errorf("%w: intervals must be positive", ErrInvalidPlan)
+	}
+	if p.Kind != models.PlanKindCalibration && p.Kind != models.PlanKindService {
+		return fmt.Errorf("%w: kind must be calibration or service", ErrInvalidPlan)
+	}
+	// 校准过期的物品不得借出，不可关闭
+	if p.Kind == models.PlanKindCalibration {
+		p.BlocksBorrowing = true
+	}
+	return nil
+}
+
+func (r *Repo) CreateMaintenancePlan(ctx context.Context, p *models.MaintenancePlan) error {
+	if err := validatePlan(p); err != nil {
+		return err
+	}
+	if _, err := r.FindItemByID(ctx, p.ItemID); err != nil {
+		return err
+	}
+	base := time.Now().UTC()
+	if p.LastDoneAt != nil {
+		base = *p.LastDoneAt
+	}
+	p.ID = uuid.NewString()
+	p.Active = true
+	p.NextDueAt = planNextDue(p, base)
+	return r.DB.WithContext(ctx).Create(p).Error
+}
+
+// UpdateMaintenancePlan 整体替换可编辑字段，并按上次完成时间重算下次到期
+func (r *Repo) UpdateMaintenancePlan(ctx context.Context, p *models.MaintenancePlan) (*models.MaintenancePlan, error) {
+	if err := validatePlan(p); err != nil {
+		return nil, err
+	}
+	var cur models.MaintenancePlan
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&cur, "id = ?", p.ID).Error; err != nil {
+			return err
+		}
+		cur.Kind = p.Kind
+		cur.Title = p.Title
+		cur.IntervalDays = p.IntervalDays
+		cur.IntervalUsageHours = p.IntervalUsageHours
+		cur.BlocksBorrowing = p.BlocksBorrowing
+		if p.LastDoneAt != nil {
+			cur.LastDoneAt = p.LastDoneAt
+		}
+		base := cur.CreatedAt
+		if cur.LastDoneAt != nil {
+			base = *cur.LastDoneAt
+		}
+		cur.NextDueAt = planNextDue(&cur, base)
+		return tx.Save(&cur).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cur, nil
+}
+
+// DeactivateMaintenancePlan 停用（保留历史工单的关联）
+func (r *Repo) DeactivateMaintenancePlan(ctx context.Context, id string) error {
+	res := r.DB.WithContext(ctx).Model(&models.MaintenancePlan{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"active": false, "updated_at": time.Now()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+type MaintenancePlanRow struct {
+	models.MaintenancePlan
+	Serial     string  `json:"serial"`
+	Name       string  `json:"name"`
+	UsageHours float64 `json:"usageHours"` // 自上次完成以来
+	Due        bool    `json:"due"`
+}
+
+func (r *Repo) planRows(ctx context.Context) *gorm.DB {
+	return r.DB.WithContext(ctx).
+		Table(models.MaintenancePlanTable + " p").
+		Select("p.*, i.serial, i.name, " + planUsageHoursSQL + " AS usage_hours, " + planDueSQL + " AS due").
+		Joins("JOIN " + models.ItemTable + " i ON i.id = p.item_id")
+}
+
+func (r *Repo) ListMaintenancePlans(ctx context.Context, itemID string) ([]MaintenancePlanRow, error) {
+	var rows []MaintenancePlanRow
+	err := r.planRows(ctx).
+		Where("p.item_id = ?", itemID).
+		Order("p.active DESC, p.next_due_at ASC NULLS LAST").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ListUpcomingMaintenance 已到期或 within 内到期的计划；按使用小时计的计划在用量达到 90% 时也列出
+func (r *Repo) ListUpcomingMaintenance(ctx context.Context, within time.Duration) ([]MaintenancePlanRow, error) {
+	var rows []MaintenancePlanRow
+	err := r.planRows(ctx).
+		Where("p.active = TRUE").
+		Where(`(p.next_due_at IS NOT NULL AND p.next_due_at <= ?)
+			OR (p.interval_usage_hours IS NOT NULL AND `+planUsageHoursSQL+` >= p.interval_usage_hours * 0.9)`,
+			time.Now().Add(within)).
+		Order("p.next_due_at ASC NULLS LAST").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ---------- 工单 ----------
+
+func (r *Repo) CreateWorkOrder(ctx context.Context, wo *models.WorkOrder) error {
+	if _, err := r.FindItemByID(ctx, wo.ItemID); err != nil {
+		return err
+	}
+	if wo.PlanID != nil {
+		var p models.MaintenancePlan
+		if err := r.DB.WithContext(ctx).First(&p, "id = ? AND item_id = ?", *wo.PlanID, wo.ItemID).Error; err != nil {
+			return err
+		}
+		if wo.Title == "" {
+			wo.Title = p.Title
+		}
+	}
+	wo.ID = uuid.NewString()
+	wo.Status = models.WorkOrderOpen
+	return r.DB.WithContext(ctx).Create(wo).Error
+}
+
+// ReportFault 借用人对手上的物品报修，生成一张 fault 工单
+func (r *Repo) ReportFault(ctx context.Context, loanID, userID, title, description string) (*models.WorkOrder, error) {
+	l, err := r.FindLoanByID(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if l.UserID != userID {
+		return nil, ErrNotLoanOwner
+	}
+	if l.ReturnedAt != nil {
+		return nil, ErrLoanClosed
+	}
+	if title == "" {
+		title = "Fault reported by borrower"
+	}
+	wo := &models.WorkOrder{
+		ItemID:      l.ItemID,
+		LoanID:      &l.ID,
+		Source:      models.WorkOrderFault,
+		Title:       title,
+		Description: description,
+		ReportedBy:  userID,
+	}
+	if err := r.CreateWorkOrder(ctx, wo); err != nil {
+		return nil, err
+	}
+	return wo, nil
+}
+
+// UpdateWorkOrderStatus 推进工单；完成关联计划的工单时刷新计划的上次完成/下次到期
+func (r *Repo) UpdateWorkOrderStatus(ctx context.Context, id, to, resolution, actorID string) (*models.WorkOrder, error) {
+	var wo models.WorkOrder
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&wo, "id = ?", id).Error; err != nil {
+			return err
+		}
+		allowed := map[string][]string{
+			models.WorkOrderOpen:       {models.WorkOrderInProgress, models.WorkOrderDone},
+			models.WorkOrderInProgress: {models.WorkOrderDone, models.WorkOrderOpen},
+		}
+		ok := false
+		for _, s := range allowed[wo.Status] {
+			ok = ok || s == to
+		}
+		if !ok {
+			return fmt.Errorf("%w: %s → %s", ErrInvalidWorkOrderMove, wo.Status, to)
+		}
+
+		now := time.Now().UTC()
+		wo.Status = to
+		switch to {
+		case models.WorkOrderInProgress:
+			wo.StartedAt = &now
+		case models.WorkOrderDone:
+			wo.CompletedAt = &now
+			wo.CompletedBy = &actorID
+			wo.Resolution = resolution
+		}
+		if err := tx.Save(&wo).Error; err != nil {
+			return err
+		}
+
+		if to == models.WorkOrderDone && wo.PlanID != nil {
+			var p models.MaintenancePlan
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				First(&p, "id = ?", *wo.PlanID).Error; err != nil {
+				return err
+			}
+			p.LastDoneAt = &now
+			p.NextDueAt = planNextDue(&p, now)
+			return tx.Save(&p).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &wo, nil
+}
+
+type WorkOrdersQuery struct {
+	ItemID string
+	Status string
+	Source string
+}
+
+type WorkOrderRow struct {
+	models.WorkOrder
+	Serial             string `json:"serial"`
+	Name               string `json:"name"`
+	ReportedByUsername string `json:"reportedByUsername"`
+}
+
+func (r *Repo) ListWorkOrders(ctx context.Context, q WorkOrdersQuery) ([]WorkOrderRow, error) {
+	tx := r.DB.WithContext(ctx).
+		Table(models.WorkOrderTable + " w").
+		Select("w.*, i.serial, i.name, u.username AS reported_by_username").
+		Joins("JOIN " + models.ItemTable + " i ON i.id = w.item_id").
+		Joins("LEFT JOIN lsb_users u ON u.id = w.reported_by")
+	if q.ItemID != "" {
+		tx = tx.Where("w.item_id = ?", q.ItemID)
+	}
+	if q.Status != "" {
+		tx = tx.Where("w.status = ?", q.Status)
+	}
+	if q.Source != "" {
+		tx = tx.Where("w.source = ?", q.Source)
+	}
+	var rows []WorkOrderRow
+	err := tx.Order("w.created_at DESC").Scan(&rows).Error
+	return rows, err
+}
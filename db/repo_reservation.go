@@ -0,0 +1,202 @@
+// db/repo_reservation.go
+package db
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrReservationConflict = errors.New("item is reserved for this period")
+	ErrInvalidReservation  = errors.New("invalid reservation period")
+	ErrNotReservationOwner = errors.New("not the owner of this reservation")
+)
+
+// checkReservationConflict 借出前校验：[start, end) 不得与“其他人”的有效预约重叠
+// end 为 nil 表示不定期借出，此后任何他人预约都算冲突
+func checkReservationConflict(tx *gorm.DB, itemID, userID string, start time.Time, end *time.Time) error {
+	q := tx.Model(&models.Reservation{}).
+		Where("item_id = ? AND user_id <> ? AND status = ?", itemID, userID, models.ReservationConfirmed).
+		Where("end_at > ?", start)
+	if end != nil {
+		q = q.Where("start_at < ?", *end)
+	}
+	var n int64
+	if err := q.Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrReservationConflict
+	}
+	return nil
+}
+
+type CreateReservationInput struct {
+	ItemID  string
+	UserID  string
+	StartAt time.Time
+	EndAt   time.Time
+	Note    string
+}
+
+// 预约：锁住 item（与借出串行）→ 校验当前借用 → 插入（排他约束兜底）
+func (r *Repo) CreateReservation(ctx context.Context, in CreateReservationInput) (*models.Reservation, error) {
+	start, end := in.StartAt.UTC(), in.EndAt.UTC()
+	if !end.After(start) || end.Before(time.Now()) {
+		return nil, ErrInvalidReservation
+	}
+
+	var res *models.Reservation
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var it models.Item
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&it, "id = ? AND status = 'active' AND archived_at IS NULL", in.ItemID).Error; err != nil {
+			return err
+		}
+
+		// 他人手上的借用若在预约开始后才到期（或不定期），视为冲突
+		var n int64
+		if err := tx.Model(&models.Loan{}).
+			Where("item_id = ? AND returned_at IS NULL AND user_id <> ?", in.ItemID, in.UserID).
+			Where("due_at IS NULL OR due_at > ?", start).
+			Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrReservationConflict
+		}
+
+		rv := &models.Reservation{
+			ID:      uuid.NewString(),
+			ItemID:  in.ItemID,
+			UserID:  in.UserID,
+			StartAt: start,
+			EndAt:   end,
+			Status:  models.ReservationConfirmed,
+			Note:    in.Note,
+		}
+		if err := tx.Create(rv).Error; err != nil {
+			if isExclusionViolation(err) {
+				return ErrReservationConflict
+			}
+			return err
+		}
+		res = rv
+		return nil
+	})
+	return res, err
+}
+
+// 取消：本人或管理员；已取消则幂等返回
+func (r *Repo) CancelReservation(ctx context.Context, id, actorID string, isAdmin bool) (*models.Reservation, error) {
+	var rv models.Reservation
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&rv, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if rv.UserID != actorID && !isAdmin {
+			return ErrNotReservationOwner
+		}
+		if rv.Status == models.ReservationCancelled {
+			return nil
+		}
+		now := time.Now().UTC()
+		rv.Status = models.ReservationCancelled
+		rv.CancelledAt = &now
+		rv.CancelledBy = &actorID
+		return tx.Save(&rv).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rv, nil
+}
+
+type ReservationsQuery struct {
+	UserID string
+	ItemID string
+	Status string     // "", "confirmed", "cancelled"
+	From   *time.Time // 与 [From, To) 有交集
+	To     *time.Time
+}
+
+func (r *Repo) ListReservations(ctx context.Context, q ReservationsQuery) ([]models.Reservation, error) {
+	tx := r.DB.WithContext(ctx).Model(&models.Reservation{}).Order("start_at ASC")
+	if q.UserID != "" {
+		tx = tx.Where("user_id = ?", q.UserID)
+	}
+	if q.ItemID != "" {
+		tx = tx.Where("item_id = ?", q.ItemID)
+	}
+	if q.Status != "" {
+		tx = tx.Where("status = ?", q.Status)
+	}
+	if q.From != nil {
+		tx = tx.Where("end_at > ?", *q.From)
+	}
+	if q.To != nil {
+		tx = tx.Where("start_at < ?", *q.To)
+	}
+	var rs []models.Reservation
+	if err := tx.Find(&rs).Error; err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// 可用性时间线：窗口内的占用段（当前借用 + 有效预约），按开始时间排序
+type BusySlot struct {
+	Kind    string     `json:"kind"` // "loan" / "reservation"
+	ID      string     `json:"id"`
+	UserID  string     `json:"userId"`
+	StartAt time.Time  `json:"startAt"`
+	EndAt   *time.Time `json:"endAt,omitempty"` // nil = 不定期借用
+}
+
+type ItemAvailability struct {
+	ItemID string     `json:"itemId"`
+	From   time.Time  `json:"from"`
+	To     time.Time  `json:"to"`
+	Busy   []BusySlot `json:"busy"`
+}
+
+func (r *Repo) GetItemAvailability(ctx context.Context, itemID string, from, to time.Time) (*ItemAvailability, error) {
+	if _, err := r.FindItemByID(ctx, itemID); err != nil {
+		return nil, err
+	}
+	out := &ItemAvailability{ItemID: itemID, From: from, To: to, Busy: []BusySlot{}}
+
+	var loans []models.Loan
+	if err := r.DB.WithContext(ctx).
+		Where("item_id = ? AND returned_at IS NULL", itemID).
+		Where("due_at IS NULL OR due_at > ?", from).
+		Where("borrowed_at < ?", to).
+		Find(&loans).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range loans {
+		out.Busy = append(out.Busy, BusySlot{Kind: "loan", ID: l.ID, UserID: l.UserID, StartAt: l.BorrowedAt, EndAt: l.DueAt})
+	}
+
+	rs, err := r.ListReservations(ctx, ReservationsQuery{
+		ItemID: itemID, Status: models.ReservationConfirmed, From: &from, To: &to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, rv := range rs {
+		end := rv.EndAt
+		out.Busy = append(out.Busy, BusySlot{Kind: "reservation", ID: rv.ID, UserID: rv.UserID, StartAt: rv.StartAt, EndAt: &end})
+	}
+	sort.Slice(out.Busy, func(i, j int) bool { return out.Busy[i].StartAt.Before(out.Busy[j].StartAt) })
+	return out, nil
+}
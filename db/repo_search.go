@@ -0,0 +1,140 @@
+// db/repo_search.go
+package db
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 全文 + 三元组检索：to_tsvector 做词前缀匹配，pg_trgm 的 GIN 索引兜底子串（LIKE）与拼写相近（%）
+// searchVector 须与 Migrate 里建索引的表达式一致，否则用不上索引
+func searchVector(a, b string) string {
+	return "to_tsvector('simple', COALESCE(" + a + ", '') || ' ' || COALESCE(" + b + ", ''))"
+}
+
+// textSearch 一次搜索的各种形态；q 为空时为 nil
+type textSearch struct {
+	lower   string // 小写原文，用于相似度与等值
+	like    string // %q%
+	prefix  string // q%
+	tsquery string // "tok1:* & tok2:*"；没有可用词元时为空
+}
+
+func newTextSearch(q string) *textSearch {
+	q = strings.ToLower(strings.TrimSpace(q))
+	if q == "" {
+		return nil
+	}
+	esc := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q)
+	// 只保留字母数字，避免 to_tsquery 语法错误
+	toks := strings.FieldsFunc(q, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for i, t := range toks {
+		toks[i] = t + ":*"
+	}
+	return &textSearch{
+		lower:   q,
+		like:    "%" + esc + "%",
+		prefix:  esc + "%",
+		tsquery: strings.Join(toks, " & "),
+	}
+}
+
+// searchCols 某张表参与检索的两列及其 tsvector 表达式；alias 为空或形如 "i."
+type searchCols struct {
+	vector string
+	a, b   string
+}
+
+func newSearchCols(a, b string) searchCols {
+	return searchCols{vector: searchVector(a, b), a: a, b: b}
+}
+
+func itemSearchCols(alias string) searchCols {
+	return newSearchCols(alias+"serial", alias+"name")
+}
+
+func userSearchCols(alias string) searchCols {
+	return newSearchCols(alias+"username", alias+"display_name")
+}
+
+// where 命中条件：词前缀 或 子串 或 三元组相似
+func (s *textSearch) where(tx *gorm.DB, c searchCols) *gorm.DB {
+	sql := "LOWER(" + c.a + ") LIKE ? OR LOWER(" + c.b + ") LIKE ? OR LOWER(" + c.a + ") % ? OR LOWER(" + c.b + ") % ?"
+	args := []any{s.like, s.like, s.lower, s.lower}
+	if s.tsquery != "" {
+		sql = c.vector + " @@ to_tsquery('simple', ?) OR " + sql
+		args = append([]any{s.tsquery}, args...)
+	}
+	return tx.Where("("+sql+")", args...)
+}
+
+// order 相关度：完全相等 > 前缀 > 全文得分 + 相似度；同分按 tiebreak
+func (s *textSearch) order(tx *gorm.DB, c searchCols, tiebreak string) *gorm.DB {
+	sql := `CASE WHEN LOWER(` + c.a + `) = ? OR LOWER(` + c.b + `) = ? THEN 2
+		WHEN LOWER(` + c.a + `) LIKE ? OR LOWER(` + c.b + `) LIKE ? THEN 1 ELSE 0 END
+		+ GREATEST(similarity(LOWER(` + c.a + `), ?), similarity(LOWER(` + c.b + `), ?))`
+	args := []any{s.lower, s.lower, s.prefix, s.prefix, s.lower, s.lower}
+	if s.tsquery != "" {
+		sql += " + ts_rank(" + c.vector + ", to_tsquery('simple', ?))"
+		args = append(args, s.tsquery)
+	}
+	return tx.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:                "(" + sql + ") DESC, " + tiebreak,
+		Vars:               args,
+		WithoutParentheses: true,
+	}})
+}
+
+// ---------- 输入联想 ----------
+
+type ItemSuggestion struct {
+	ID     string `json:"id"`
+	Serial string `json:"serial"`
+	Name   string `json:"name"`
+	InUse  bool   `json:"inUse"`
+}
+
+type UserSuggestion struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+}
+
+// SuggestItems 按相关度取前 limit 个物品（不含已报废、已归档）
+func (r *Repo) SuggestItems(ctx context.Context, q string, limit int) ([]ItemSuggestion, error) {
+	out := []ItemSuggestion{}
+	s := newTextSearch(q)
+	if s == nil {
+		return out, nil
+	}
+	tx := r.DB.WithContext(ctx).
+		Table(models.ItemTable+" i").
+		Select("i.id, i.serial, i.name, i.in_use").
+		Where("i.status <> ? AND i.archived_at IS NULL", models.ItemStatusRetired)
+	tx = s.where(tx, itemSearchCols("i."))
+	tx = s.order(tx, itemSearchCols("i."), "i.serial ASC")
+	err := tx.Limit(limit).Scan(&out).Error
+	return out, err
+}
+
+// SuggestUsers 按相关度取前 limit 个用户
+func (r *Repo) SuggestUsers(ctx context.Context, q string, limit int) ([]UserSuggestion, error) {
+	out := []UserSuggestion{}
+	s := newTextSearch(q)
+	if s == nil {
+		return out, nil
+	}
+	tx := r.DB.WithContext(ctx).
+		Table("lsb_users u").
+		Select("u.id, u.username, u.display_name")
+	tx = s.where(tx, userSearchCols("u."))
+	tx = s.order(tx, userSearchCols("u."), "u.username ASC")
+	err := tx.Limit(limit).Scan(&out).Error
+	return out, err
+}
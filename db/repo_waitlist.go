@@ -0,0 +1,278 @@
+// db/repo_waitlist.go
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrClaimedByOther     = errors.New("item is held for the next person on the waitlist")
+	ErrAlreadyWaitlisted  = errors.New("already on the waitlist for this item")
+	ErrWaitlistNotNeeded  = errors.New("item is available, borrow it directly")
+	ErrNotOnWaitlist      = errors.New("not on the waitlist for this item")
+	ErrBorrowerCannotWait = errors.New("you already hold this item")
+)
+
+// 认领时限：WAITLIST_CLAIM_MINUTES，默认 30 分钟
+func waitlistClaimTTL() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("WAITLIST_CLAIM_MINUTES")); err == nil && v > 0 {
+		return time.Duration(v) * time.Minute
+	}
+	return 30 * time.Minute
+}
+
+// advanceWaitlist 推进某物品的排队（调用方须已锁住 item 行）：
+// 过期认领 → expired；物品在用（active）、空闲且无有效认领时，把认领交给队首
+func advanceWaitlist(tx *gorm.DB, itemID string, now time.Time) error {
+	if err := tx.Model(&models.WaitlistEntry{}).
+		Where("item_id = ? AND status = ? AND claim_expires_at <= ?", itemID, models.WaitlistOffered, now).
+		Updates(map[string]any{"status": models.WaitlistExpired, "updated_at": now}).Error; err != nil {
+		return err
+	}
+
+	var offered int64
+	if err := tx.Model(&models.WaitlistEntry{}).
+		Where("item_id = ? AND status = ?", itemID, models.WaitlistOffered).
+		Count(&offered).Error; err != nil {
+		return err
+	}
+	if offered > 0 {
+		return nil
+	}
+
+	var it models.Item
+	if err := tx.Select("in_use", "status").Where("id = ?", itemID).Take(&it).Error; err != nil {
+		return err
+	}
+	if it.InUse || it.Status != models.ItemStatusActive {
+		return nil
+	}
+
+	var next models.WaitlistEntry
+	err := tx.Where("item_id = ? AND status = ?", itemID, models.WaitlistWaiting).
+		Order("created_at ASC").
+		Take(&next).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	exp := now.Add(waitlistClaimTTL())
+	return tx.Model(&models.WaitlistEntry{}).
+		Where("id = ?", next.ID).
+		Updates(map[string]any{
+			"status":           models.WaitlistOffered,
+			"offered_at":       now,
+			"claim_expires_at": exp,
+			"updated_at":       now,
+		}).Error
+}
+
+// checkWaitlistClaim 借出前校验（调用方须已锁住 item 行）：
+// 有他人持有的有效认领则拒绝；若认领属于借用人，返回该记录以便借出后标记 fulfilled
+func checkWaitlistClaim(tx *gorm.DB, itemID, userID string, now time.Time) (*models.WaitlistEntry, error) {
+	if err := advanceWaitlist(tx, itemID, now); err != nil {
+		return nil, err
+	}
+	var e models.WaitlistEntry
+	err := tx.Where("item_id = ? AND status = ?", itemID, models.WaitlistOffered).Take(&e).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if e.UserID != userID {
+		return nil, ErrClaimedByOther
+	}
+	return &e, nil
+}
+
+// fulfillWaitlistClaim 认领人借到物品后关闭其排队记录
+func fulfillWaitlistClaim(tx *gorm.DB, e *models.WaitlistEntry, loanID string, now time.Time) error {
+	if e == nil {
+		return nil
+	}
+	return tx.Model(&models.WaitlistEntry{}).
+		Where("id = ?", e.ID).
+		Updates(map[string]any{
+			"status":     models.WaitlistFulfilled,
+			"loan_id":    loanID,
+			"updated_at": now,
+		}).Error
+}
+
+// 加入排队：仅当物品被借出或已被他人认领时才需要排队
+func (r *Repo) JoinWaitlist(ctx context.Context, itemID, userID string) (*models.WaitlistEntry, error) {
+	var entry *models.WaitlistEntry
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var it models.Item
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&it, "id = ? AND status = 'active' AND archived_at IS NULL", itemID).Error; err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		if err := advanceWaitlist(tx, itemID, now); err != nil {
+			return err
+		}
+
+		if it.InUse {
+			var mine int64
+			if err := tx.Model(&models.Loan{}).
+				Where("item_id = ? AND user_id = ? AND returned_at IS NULL", itemID, userID).
+				Count(&mine).Error; err != nil {
+				return err
+			}
+			if mine > 0 {
+				return ErrBorrowerCannotWait
+			}
+		} else {
+			var others int64
+			if err := tx.Model(&models.WaitlistEntry{}).
+				Where("item_id = ? AND status = ? AND user_id <> ?", itemID, models.WaitlistOffered, userID).
+				Count(&others).Error; err != nil {
+				return err
+			}
+			if others == 0 {
+				return ErrWaitlistNotNeeded
+			}
+		}
+
+		e := &models.WaitlistEntry{
+			ID:     uuid.NewString(),
+			ItemID: itemID,
+			UserID: userID,
+			Status: models.WaitlistWaiting,
+		}
+		if err := tx.Create(e).Error; err != nil {
+			if isUniqueViolation(err) {
+				return ErrAlreadyWaitlisted
+			}
+			return err
+		}
+		entry = e
+		return nil
+	})
+	return entry, err
+}
+
+// 退出排队：若放弃的是认领，立即顺延给下一位
+func (r *Repo) LeaveWaitlist(ctx context.Context, itemID, userID string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var it models.Item
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&it, "id = ?", itemID).Error; err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		res := tx.Model(&models.WaitlistEntry{}).
+			Where("item_id = ? AND user_id = ? AND status IN ?", itemID, userID,
+				[]string{models.WaitlistWaiting, models.WaitlistOffered}).
+			Updates(map[string]any{"status": models.WaitlistCancelled, "updated_at": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotOnWaitlist
+		}
+		return advanceWaitlist(tx, itemID, now)
+	})
+}
+
+type WaitlistRow struct {
+	ID             string     `json:"id"`
+	ItemID         string     `json:"itemId"`
+	Serial         string     `json:"serial"`
+	Name           string     `json:"name"`
+	UserID         string     `json:"userId"`
+	Username       string     `json:"username"`
+	Status         string     `json:"status"`
+	Position       int        `json:"position"` // 1 = 队首（含认领中的那位）
+	ClaimExpiresAt *time.Time `json:"claimExpiresAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// 有效排队记录 + 队内位置
+func (r *Repo) waitlistRows(ctx context.Context) *gorm.DB {
+	ranked := r.DB.WithContext(ctx).
+		Table(models.WaitlistTable+" w").
+		Select(`
+			w.id, w.item_id, w.user_id, w.status, w.claim_expires_at, w.created_at,
+			ROW_NUMBER() OVER (
+				PARTITION BY w.item_id
+				ORDER BY (w.status = 'offered') DESC, w.created_at ASC
+			) AS position
+		`).
+		Where("w.status IN ?", []string{models.WaitlistWaiting, models.WaitlistOffered})
+
+	return r.DB.WithContext(ctx).
+		Table("(?) AS q", ranked).
+		Select(`
+			q.id, q.item_id, i.serial, i.name, q.user_id, u.username,
+			q.status, q.position, q.claim_expires_at, q.created_at
+		`).
+		Joins("JOIN " + models.ItemTable + " i ON i.id = q.item_id").
+		Joins("JOIN lsb_users u ON u.id = q.user_id")
+}
+
+func (r *Repo) ListItemWaitlist(ctx context.Context, itemID string) ([]WaitlistRow, error) {
+	var rows []WaitlistRow
+	err := r.waitlistRows(ctx).
+		Where("q.item_id = ?", itemID).
+		Order("q.position ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *Repo) ListMyWaitlist(ctx context.Context, userID string) ([]WaitlistRow, error) {
+	var rows []WaitlistRow
+	err := r.waitlistRows(ctx).
+		Where("q.user_id = ?", userID).
+		Order("q.created_at ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// SweepWaitlist 后台兜底：处理所有“空闲但队列没动”的物品（认领过期 / 归还时未推进）
+// 每件物品单独加行锁推进，多副本并发执行也安全
+func (r *Repo) SweepWaitlist(ctx context.Context) (int, error) {
+	var itemIDs []string
+	if err := r.DB.WithContext(ctx).
+		Table(models.WaitlistTable+" w").
+		Distinct("w.item_id").
+		Joins("JOIN "+models.ItemTable+" i ON i.id = w.item_id").
+		Where("i.in_use = FALSE").
+		Where(`(w.status = ? AND w.claim_expires_at <= NOW())
+			OR (w.status = ? AND NOT EXISTS (
+				SELECT 1 FROM `+models.WaitlistTable+` o
+				WHERE o.item_id = w.item_id AND o.status = ?))`,
+			models.WaitlistOffered, models.WaitlistWaiting, models.WaitlistOffered).
+		Pluck("w.item_id", &itemIDs).Error; err != nil {
+		return 0, err
+	}
+
+	for _, id := range itemIDs {
+		if err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var it models.Item
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				First(&it, "id = ?", id).Error; err != nil {
+				return err
+			}
+			return advanceWaitlist(tx, id, time.Now().UTC())
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return len(itemIDs), nil
+}
@@ -0,0 +1,125 @@
+// export/export.go
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Writer 逐行写表格；Close 负责收尾（CSV 刷缓冲，XLSX 输出整个文件）
+type Writer interface {
+	WriteRow(cells []any) error
+	Close() error
+}
+
+// Format → Content-Type / 扩展名
+var Formats = map[string]struct{ ContentType, Ext string }{
+	"csv":  {"text/csv; charset=utf-8", "csv"},
+	"xlsx": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"},
+}
+
+func New(format string, w io.Writer, sheet string) (Writer, error) {
+	switch format {
+	case "csv":
+		return newCSV(w)
+	case "xlsx":
+		return newXLSX(w, sheet)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// ---------- CSV：每 200 行刷一次，边查边写 ----------
+
+type csvWriter struct {
+	cw *csv.Writer
+	n  int
+}
+
+func newCSV(w io.Writer) (*csvWriter, error) {
+	// 写 BOM，Excel 直接打开时中文不乱码
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return nil, err
+	}
+	return &csvWriter{cw: csv.NewWriter(w)}, nil
+}
+
+func (c *csvWriter) WriteRow(cells []any) error {
+	rec := make([]string, len(cells))
+	for i, v := range cells {
+		rec[i] = cellString(v)
+	}
+	if err := c.cw.Write(rec); err != nil {
+		return err
+	}
+	if c.n++; c.n%200 == 0 {
+		c.cw.Flush()
+		return c.cw.Error()
+	}
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	c.cw.Flush()
+	return c.cw.Error()
+}
+
+// ---------- XLSX：行数据由 StreamWriter 超过阈值落临时文件；收尾时 zip 直接写到 out ----------
+// 注意 excelize 的 Write / SaveAs 都会先在内存里拼出整个文件（WriteToBuffer），这里不用它们
+
+type xlsxWriter struct {
+	f   *excelize.File
+	sw  *excelize.StreamWriter
+	row int
+}
+
+func newXLSX(w io.Writer, sheet string) (*xlsxWriter, error) {
+	f := excelize.NewFile()
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	// 忽略 excelize 传进来的内存 buffer，压缩结果直接流向 w
+	f.SetZipWriter(func(io.Writer) excelize.ZipWriter { return zip.NewWriter(w) })
+	return &xlsxWriter{f: f, sw: sw}, nil
+}
+
+func (x *xlsxWriter) WriteRow(cells []any) error {
+	x.row++
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.sw.SetRow(cell, cells)
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.f.Close() // 清理临时文件
+	if err := x.sw.Flush(); err != nil {
+		return err
+	}
+	// 经 SetZipWriter 已写到 out，返回的 buffer 为空
+	_, err := x.f.WriteToBuffer()
+	return err
+}
+
+func cellString(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case fmt.Stringer:
+		return t.String()
+	default:
+		return fmt.Sprint(t)
+	}
+}
@@ -0,0 +1,144 @@
+// invoice/invoice.go
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Doc 一张账单的打印内容；金额单位为分，付款与减免为负数
+type Doc struct {
+	Number      string
+	Period      string
+	Customer    string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Currency    string
+	GeneratedAt time.Time
+
+	OpeningCents  int64
+	ChargesCents  int64
+	CreditsCents  int64
+	PaymentsCents int64
+	ClosingCents  int64
+
+	Lines []Line
+}
+
+type Line struct {
+	Date        time.Time
+	Kind        string
+	Item        string // 物品编号，无关联借用时为空
+	Description string
+	AmountCents int64
+}
+
+// FormatCents 1234 → "12.34"，-5 → "-0.05"
+func FormatCents(c int64) string {
+	sign := ""
+	if c < 0 {
+		sign, c = "-", -c
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, c/100, c%100)
+}
+
+// 列宽（A4 纵向，左右边距 15mm，可用 180mm）
+var cols = []struct {
+	title string
+	w     float64
+	align string
+}{
+	{"Date", 24, "L"},
+	{"Type", 22, "L"},
+	{"Item", 30, "L"},
+	{"Description", 76, "L"},
+	{"Amount", 28, "R"},
+}
+
+// PDF 输出 A4 账单，明细超出一页自动换页并重复表头
+// 内置 Helvetica 只覆盖 cp1252，中文等字符会被替换为 '?'
+func PDF(d Doc) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	header := func() {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(230, 230, 230)
+		for _, c := range cols {
+			pdf.CellFormat(c.w, 7, c.title, "1", 0, c.align, true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 9)
+	}
+	pdf.SetHeaderFunc(func() {
+		if pdf.PageNo() > 1 {
+			header()
+		}
+	})
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, "Invoice "+tr(d.Number), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, "Customer: "+tr(d.Customer), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, fmt.Sprintf("Period: %s (%s - %s UTC)", d.Period,
+		d.PeriodStart.Format("2006-01-02"), d.PeriodEnd.Add(-time.Second).Format("2006-01-02")), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Generated: "+d.GeneratedAt.UTC().Format("2006-01-02 15:04")+" UTC", "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Currency: "+d.Currency, "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	header()
+	if len(d.Lines) == 0 {
+		pdf.CellFormat(180, 7, "No activity in this period.", "1", 1, "C", false, 0, "")
+	}
+	for _, l := range d.Lines {
+		vals := []string{
+			l.Date.UTC().Format("2006-01-02"),
+			l.Kind,
+			tr(l.Item),
+			tr(l.Description),
+			FormatCents(l.AmountCents),
+		}
+		for i, c := range cols {
+			pdf.CellFormat(c.w, 6, fit(pdf, vals[i], c.w-2), "1", 0, c.align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.Ln(4)
+
+	total := func(label string, cents int64, bold bool) {
+		style := ""
+		if bold {
+			style = "B"
+		}
+		pdf.SetFont("Helvetica", style, 10)
+		pdf.CellFormat(152, 6, label, "", 0, "R", false, 0, "")
+		pdf.CellFormat(28, 6, FormatCents(cents), "", 1, "R", false, 0, "")
+	}
+	total("Opening balance", d.OpeningCents, false)
+	total("Charges", d.ChargesCents, false)
+	total("Credits", d.CreditsCents, false)
+	total("Payments", d.PaymentsCents, false)
+	total("Balance due", d.ClosingCents, true)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fit 超出列宽时截断并加省略号；s 已经过 cp1252 转换，按字节截即可
+func fit(pdf *fpdf.Fpdf, s string, w float64) string {
+	if pdf.GetStringWidth(s) <= w {
+		return s
+	}
+	for len(s) > 0 && pdf.GetStringWidth(s+"...") > w {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
@@ -0,0 +1,102 @@
+// labels/labels.go
+package labels
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Label 一张物品标签：二维码内容是深链接，下方印编号和名称
+type Label struct {
+	Link   string
+	Serial string
+	Name   string
+}
+
+const (
+	textLine  = 16 // 每行文字高度（px，basicfont 7x13）
+	textPad   = 6
+	maxChars  = 40 // 名称过长截断
+	svgModule = 8  // SVG 中每个模块的边长
+)
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// PNG 输出 size×size 的二维码，下面追加两行文字（编号 / 名称）
+// basicfont 只有 ASCII 字形，非 ASCII 字符会显示为方块；需要中文时用 SVG 或 PDF
+func PNG(l Label, size int) ([]byte, error) {
+	q, err := qrcode.New(l.Link, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	qr := q.Image(size)
+
+	w := size
+	h := size + 2*textLine + textPad
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, qr.Bounds(), qr, image.Point{}, draw.Src)
+
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(color.Black), Face: basicfont.Face7x13}
+	line := func(s string, y int) {
+		s = truncate(s, w/7)
+		adv := d.MeasureString(s).Round()
+		d.Dot = fixed.P((w-adv)/2, y)
+		d.DrawString(s)
+	}
+	line(l.Serial, size+textLine)
+	line(l.Name, size+2*textLine)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG 矢量标签，适合打印机按任意尺寸缩放
+func SVG(l Label) ([]byte, error) {
+	q, err := qrcode.New(l.Link, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	bm := q.Bitmap() // 已含静区
+	n := len(bm)
+	side := n * svgModule
+	h := side + textLine*5
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, side, h, side, h)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/>`, side, h)
+	buf.WriteString(`<path fill="#000" d="`)
+	for y, row := range bm {
+		for x, on := range row {
+			if on {
+				fmt.Fprintf(&buf, "M%d %dh%dv%dh-%dz", x*svgModule, y*svgModule, svgModule, svgModule, svgModule)
+			}
+		}
+	}
+	buf.WriteString(`"/>`)
+	fmt.Fprintf(&buf, `<text x="%d" y="%d" font-family="monospace" font-size="24" font-weight="bold" text-anchor="middle">%s</text>`,
+		side/2, side+textLine*2, html.EscapeString(l.Serial))
+	fmt.Fprintf(&buf, `<text x="%d" y="%d" font-family="sans-serif" font-size="20" text-anchor="middle">%s</text>`,
+		side/2, side+textLine*4, html.EscapeString(truncate(l.Name, maxChars)))
+	buf.WriteString(`</svg>`)
+	return buf.Bytes(), nil
+}
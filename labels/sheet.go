@@ -0,0 +1,65 @@
+// labels/sheet.go
+package labels
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+)
+
+// A4 纵向 3 列 × 8 行（常见 70×37mm 不干胶标签纸）
+const (
+	sheetCols   = 3
+	sheetRows   = 8
+	cellW       = 70.0
+	cellH       = 37.0
+	sheetMargin = 0.5 // 上边距，使 8 行正好落在 297mm 内
+	qrSide      = 30.0
+)
+
+// SheetPDF 把多张标签排成可打印的 A4 PDF，超出一页自动换页
+// 内置 Helvetica 只覆盖 cp1252，名称中的中文等字符会被替换为 '?'
+func SheetPDF(ls []Label) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	perPage := sheetCols * sheetRows
+	for i, l := range ls {
+		if i%perPage == 0 {
+			pdf.AddPage()
+		}
+		pos := i % perPage
+		x := float64(pos%sheetCols) * cellW
+		y := sheetMargin + float64(pos/sheetCols)*cellH
+
+		png, err := qrcode.Encode(l.Link, qrcode.Medium, 256)
+		if err != nil {
+			return nil, err
+		}
+		name := fmt.Sprintf("qr%d", i)
+		pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(png))
+		pdf.ImageOptions(name, x+2, y+(cellH-qrSide)/2, qrSide, qrSide, false, fpdf.ImageOptions{}, 0, "")
+
+		tx := x + qrSide + 4
+		tw := cellW - qrSide - 6
+		pdf.SetXY(tx, y+8)
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.MultiCell(tw, 5, tr(l.Serial), "", "L", false)
+		pdf.SetX(tx)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.MultiCell(tw, 4, tr(truncate(l.Name, maxChars)), "", "L", false)
+	}
+	if len(ls) == 0 {
+		pdf.AddPage()
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,68 @@
+// mailer/mailer.go
+package mailer
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"strings"
+)
+
+// Conf SMTP 配置，来自环境变量
+type Conf struct {
+	Host     string // SMTP_HOST, e.g. smtp.gmail.com
+	Port     string // SMTP_PORT, e.g. 587
+	Username string // SMTP_USERNAME, e.g. your@gmail.com
+	Password string // SMTP_PASSWORD, app password or smtp password
+	From     string // SMTP_FROM,    e.g. no-reply@yourdomain.com (为空时回退 Username)
+	AppName  string // APP_NAME,     e.g. Rent Tool
+}
+
+func Load() Conf {
+	get := func(k, d string) string {
+		if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+			return v
+		}
+		return d
+	}
+	return Conf{
+		Host:     get("SMTP_HOST", ""),
+		Port:     get("SMTP_PORT", "587"),
+		Username: get("SMTP_USERNAME", ""),
+		Password: get("SMTP_PASSWORD", ""),
+		From:     get("SMTP_FROM", ""),
+		AppName:  get("APP_NAME", "Rent Tool"),
+	}
+}
+
+// Configured 未配置 SMTP 时视为开发模式，调用方应改为打印日志
+func (c Conf) Configured() bool {
+	return c.Host != "" && (c.Username != "" || c.From != "")
+}
+
+func (c Conf) fromAddr() string {
+	if c.From != "" {
+		return c.From
+	}
+	return c.Username
+}
+
+// Send 发送一封 HTML 邮件
+func (c Conf) Send(toEmail, subject, htmlBody string) error {
+	from := c.fromAddr()
+	msg := BuildMIMEWithFromName(c.AppName, from, toEmail, subject, htmlBody)
+	auth := smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	addr := c.Host + ":" + c.Port
+	return smtp.SendMail(addr, auth, from, []string{toEmail}, []byte(msg))
+}
+
+func BuildMIMEWithFromName(fromName, fromAddr, to, subject, html string) string {
+	headers := []string{
+		fmt.Sprintf("From: %s <%s>", fromName, fromAddr),
+		fmt.Sprintf("To: %s", to),
+		fmt.Sprintf("Subject: %s", subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/html; charset=UTF-8",
+	}
+	return strings.Join(headers, "\r\n") + "\r\n\r\n" + html
+}
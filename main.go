@@ -4,6 +4,8 @@ import (
 	"Gin_postgres_redis_rent_tool/app"
 	"Gin_postgres_redis_rent_tool/config"
 	"Gin_postgres_redis_rent_tool/routes"
+	"Gin_postgres_redis_rent_tool/worker"
+	"context"
 	"log"
 	"os"
 )
@@ -41,6 +43,11 @@ func main() {
 	// WebAuthn routes
 	routes.RegisterRoutes(r, application)
 
+	// 后台任务（排队认领过期顺延、到期提醒等）
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	worker.New(application).Start(ctx)
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "3001"
@@ -0,0 +1,39 @@
+// models/attachment.go
+package models
+
+import "time"
+
+const AttachmentTable = "lsb_attachments"
+
+// 附件挂在哪类对象上
+const (
+	AttachOwnerItem = "item"
+	AttachOwnerLoan = "loan"
+)
+
+// 附件种类
+const (
+	AttachKindManual      = "manual"
+	AttachKindPhoto       = "photo"
+	AttachKindCertificate = "certificate"
+	AttachKindCheckout    = "checkout_photo" // 借出时拍照
+	AttachKindReturn      = "return_photo"   // 归还时拍照
+)
+
+// Attachment 文件元数据；内容存在 BlobStore 中（BlobKey / ThumbKey）
+type Attachment struct {
+	ID          string    `gorm:"type:uuid;primaryKey" json:"id"`
+	OwnerType   string    `gorm:"size:20;not null;index:idx_attachment_owner" json:"ownerType"`
+	OwnerID     string    `gorm:"type:uuid;not null;index:idx_attachment_owner" json:"ownerId"`
+	Kind        string    `gorm:"size:20;not null" json:"kind"`
+	FileName    string    `gorm:"size:255;not null" json:"fileName"`
+	ContentType string    `gorm:"size:100;not null" json:"contentType"`
+	Size        int64     `gorm:"not null" json:"size"`
+	BlobKey     string    `gorm:"size:255;not null" json:"-"`
+	ThumbKey    *string   `gorm:"size:255" json:"-"`
+	HasThumb    bool      `gorm:"not null;default:false" json:"hasThumbnail"`
+	UploadedBy  string    `gorm:"type:uuid;not null" json:"uploadedBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (Attachment) TableName() string { return AttachmentTable }
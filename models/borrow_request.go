@@ -0,0 +1,36 @@
+// models/borrow_request.go
+package models
+
+import "time"
+
+const BorrowRequestTable = "lsb_borrow_requests"
+
+// 借用申请状态：pending → approved / rejected / cancelled / expired
+const (
+	RequestPending   = "pending"
+	RequestApproved  = "approved"
+	RequestRejected  = "rejected"
+	RequestCancelled = "cancelled"
+	RequestExpired   = "expired"
+)
+
+// BorrowRequest 需审批物品的借用申请；批准时才真正借出并生成 Loan
+// 同一用户对同一物品同一时刻最多一条 pending（Migrate 中建部分唯一索引）
+type BorrowRequest struct {
+	ID     string     `gorm:"type:uuid;primaryKey" json:"id"`
+	ItemID string     `gorm:"type:uuid;not null;index" json:"itemId"`
+	UserID string     `gorm:"type:uuid;not null;index" json:"userId"`
+	DueAt  *time.Time `json:"dueAt,omitempty"` // 申请的到期时间，空 = 默认借期
+	Note   string     `gorm:"size:255" json:"note,omitempty"`
+	Status string     `gorm:"size:20;not null;default:'pending';index" json:"status"`
+
+	ExpiresAt time.Time  `gorm:"not null" json:"expiresAt"` // 无人处理则到期作废
+	DecidedBy *string    `gorm:"type:uuid" json:"decidedBy,omitempty"`
+	DecidedAt *time.Time `json:"decidedAt,omitempty"`
+	Reason    string     `gorm:"size:255" json:"reason,omitempty"`  // 审批意见
+	LoanID    *string    `gorm:"type:uuid" json:"loanId,omitempty"` // 批准后生成的借用
+	CreatedAt time.Time  `gorm:"index" json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+func (BorrowRequest) TableName() string { return BorrowRequestTable }
@@ -0,0 +1,25 @@
+// models/category.go
+package models
+
+import "time"
+
+const CategoryTable = "lsb_categories"
+const ItemTagTable = "lsb_item_tags"
+
+// Category 物品分类，ParentID 为空即顶级；同一父级下名称唯一（Migrate 中建索引）
+type Category struct {
+	ID        string    `gorm:"type:uuid;primaryKey" json:"id"`
+	ParentID  *string   `gorm:"type:uuid;index" json:"parentId,omitempty"`
+	Name      string    `gorm:"size:120;not null" json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ItemTag 物品的自由标签（统一小写）
+type ItemTag struct {
+	ItemID string `gorm:"type:uuid;primaryKey" json:"itemId"`
+	Tag    string `gorm:"size:64;primaryKey;index" json:"tag"`
+}
+
+func (Category) TableName() string { return CategoryTable }
+func (ItemTag) TableName() string  { return ItemTagTable }
@@ -0,0 +1,39 @@
+// models/category_attribute.go
+package models
+
+import "time"
+
+const CategoryAttributeTable = "lsb_category_attributes"
+
+// 属性类型
+const (
+	AttrTypeString  = "string"
+	AttrTypeNumber  = "number"
+	AttrTypeInteger = "integer"
+	AttrTypeBoolean = "boolean"
+	AttrTypeDate    = "date" // 存 "YYYY-MM-DD"
+	AttrTypeEnum    = "enum"
+)
+
+func IsAttrType(t string) bool {
+	switch t {
+	case AttrTypeString, AttrTypeNumber, AttrTypeInteger, AttrTypeBoolean, AttrTypeDate, AttrTypeEnum:
+		return true
+	}
+	return false
+}
+
+// CategoryAttribute 分类的属性定义；子分类继承所有祖先分类的定义
+type CategoryAttribute struct {
+	ID         string      `gorm:"type:uuid;primaryKey" json:"id"`
+	CategoryID string      `gorm:"type:uuid;not null;uniqueIndex:idx_category_attr_name" json:"categoryId"`
+	Name       string      `gorm:"size:64;not null;uniqueIndex:idx_category_attr_name" json:"name"` // Item.Attributes 中的键
+	Label      string      `gorm:"size:120" json:"label,omitempty"`                                 // 显示名
+	Type       string      `gorm:"size:20;not null" json:"type"`
+	Required   bool        `gorm:"not null;default:false" json:"required"`
+	EnumValues JSONStrings `gorm:"type:jsonb;not null;default:'[]'" json:"enumValues,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	UpdatedAt  time.Time   `json:"updatedAt"`
+}
+
+func (CategoryAttribute) TableName() string { return CategoryAttributeTable }
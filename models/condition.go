@@ -0,0 +1,110 @@
+// models/condition.go
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const ChecklistItemTable = "lsb_checklist_items"
+const ConditionReportTable = "lsb_condition_reports"
+
+// 物品状况等级，由好到坏
+const (
+	ConditionGood    = "good"
+	ConditionFair    = "fair"
+	ConditionPoor    = "poor"
+	ConditionDamaged = "damaged"
+)
+
+// IsConditionGrade 是否为已知等级
+func IsConditionGrade(s string) bool {
+	switch s {
+	case ConditionGood, ConditionFair, ConditionPoor, ConditionDamaged:
+		return true
+	}
+	return false
+}
+
+// 状况记录的时机
+const (
+	ConditionAtCheckout = "checkout"
+	ConditionAtReturn   = "return"
+)
+
+// ChecklistItem 借还时需要清点的配件；Scope 为 category 时对该分类及其子分类的物品都生效
+type ChecklistItem struct {
+	ID        string    `gorm:"type:uuid;primaryKey" json:"id"`
+	Scope     string    `gorm:"size:20;not null;index:idx_checklist_scope" json:"scope"` // category / item
+	ScopeID   string    `gorm:"type:uuid;not null;index:idx_checklist_scope" json:"scopeId"`
+	Label     string    `gorm:"size:120;not null" json:"label"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ChecklistResult 一条清点结果；Label 为记录时的快照，清单日后修改不影响历史
+type ChecklistResult struct {
+	ChecklistItemID string `json:"checklistItemId"`
+	Label           string `json:"label"`
+	Present         bool   `json:"present"`
+}
+
+// ChecklistResults 存为 jsonb 数组
+type ChecklistResults []ChecklistResult
+
+func (s ChecklistResults) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]ChecklistResult(s))
+	return string(b), err
+}
+
+func (s *ChecklistResults) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("ChecklistResults: unsupported type %T", src)
+	}
+	return json.Unmarshal(b, (*[]ChecklistResult)(s))
+}
+
+// ConditionReport 借出 / 归还时的状况记录；每条借用每个时机最多一条
+// 照片沿用借用附件（checkout_photo / return_photo）
+type ConditionReport struct {
+	ID         string           `gorm:"type:uuid;primaryKey" json:"id"`
+	LoanID     string           `gorm:"type:uuid;not null;uniqueIndex:idx_condition_once" json:"loanId"`
+	Stage      string           `gorm:"size:10;not null;uniqueIndex:idx_condition_once" json:"stage"`
+	ItemID     string           `gorm:"type:uuid;not null;index" json:"itemId"`
+	Grade      string           `gorm:"size:20;not null" json:"grade"`
+	Damage     string           `gorm:"size:1000" json:"damage,omitempty"` // 损坏描述
+	Checklist  ChecklistResults `gorm:"type:jsonb;not null;default:'[]'" json:"checklist"`
+	Flagged    bool             `gorm:"not null;default:false;index" json:"flagged"` // 归还时有损坏或缺配件
+	ReportedBy string           `gorm:"type:uuid;not null" json:"reportedBy"`
+	NotifiedAt *time.Time       `json:"notifiedAt,omitempty"` // 已通知管理员
+	CreatedAt  time.Time        `json:"createdAt"`
+}
+
+// Missing 未清点到的配件
+func (r *ConditionReport) Missing() []string {
+	var out []string
+	for _, c := range r.Checklist {
+		if !c.Present {
+			out = append(out, c.Label)
+		}
+	}
+	return out
+}
+
+func (ChecklistItem) TableName() string   { return ChecklistItemTable }
+func (ConditionReport) TableName() string { return ConditionReportTable }
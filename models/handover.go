@@ -0,0 +1,35 @@
+// models/handover.go
+package models
+
+import "time"
+
+const LoanHandoverTable = "lsb_loan_handovers"
+
+// 转交状态：pending → accepted / declined / cancelled / expired
+const (
+	HandoverPending   = "pending"
+	HandoverAccepted  = "accepted"
+	HandoverDeclined  = "declined"
+	HandoverCancelled = "cancelled"
+	HandoverExpired   = "expired"
+)
+
+// LoanHandover 借用人把在借物品直接转交给另一人；接收人确认后旧借用关闭、新借用开始，不经过归还
+// 同一借用同一时刻最多一条 pending（Migrate 中建部分唯一索引）
+type LoanHandover struct {
+	ID         string `gorm:"type:uuid;primaryKey" json:"id"`
+	LoanID     string `gorm:"type:uuid;not null;index" json:"loanId"` // 转出的借用
+	ItemID     string `gorm:"type:uuid;not null;index" json:"itemId"`
+	FromUserID string `gorm:"type:uuid;not null;index" json:"fromUserId"`
+	ToUserID   string `gorm:"type:uuid;not null;index" json:"toUserId"`
+	Status     string `gorm:"size:20;not null;default:'pending'" json:"status"`
+	Note       string `gorm:"size:255" json:"note,omitempty"`
+
+	ExpiresAt   time.Time  `gorm:"not null" json:"expiresAt"`            // 过期未确认自动作废
+	NewLoanID   *string    `gorm:"type:uuid" json:"newLoanId,omitempty"` // 接收人的新借用
+	RespondedAt *time.Time `json:"respondedAt,omitempty"`                // 接受 / 拒绝 / 撤回的时间；接受时即转交时间
+	CreatedAt   time.Time  `gorm:"index" json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+func (LoanHandover) TableName() string { return LoanHandoverTable }
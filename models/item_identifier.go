@@ -0,0 +1,41 @@
+// models/item_identifier.go
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+const ItemIdentifierTable = "lsb_item_identifiers"
+
+// 标识类型：扫码枪/手机能读到的各种编码
+const (
+	IdentKindBarcode = "barcode" // 一维码/条形码
+	IdentKindNFC     = "nfc"     // NFC 标签 UID
+	IdentKindAlias   = "alias"   // 其它别名（旧编号等）
+)
+
+func IsIdentKind(k string) bool {
+	return k == IdentKindBarcode || k == IdentKindNFC || k == IdentKindAlias
+}
+
+// ItemIdentifier 物品的别名/标识；Value 存归一化后的值，全局唯一
+type ItemIdentifier struct {
+	ID        string    `gorm:"type:uuid;primaryKey" json:"id"`
+	ItemID    string    `gorm:"type:uuid;index;not null" json:"itemId"`
+	Kind      string    `gorm:"size:20;not null" json:"kind"`
+	Value     string    `gorm:"size:200;not null;uniqueIndex" json:"value"`
+	CreatedBy string    `gorm:"type:uuid" json:"createdBy,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (ItemIdentifier) TableName() string { return ItemIdentifierTable }
+
+// NormalizeIdentifier 去空白并转大写；NFC UID 常见 "04:A2:..." / "04-a2-..." 写法，去掉分隔符
+func NormalizeIdentifier(kind, v string) string {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	if kind == IdentKindNFC {
+		v = strings.NewReplacer(":", "", "-", "", " ", "").Replace(v)
+	}
+	return v
+}
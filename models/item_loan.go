@@ -5,15 +5,25 @@ import "time"
 
 const LoanTable = "lsb_loans"
 const ItemTable = "lsb_items"
+const LoanExtensionTable = "lsb_loan_extensions"
 
 type Item struct {
-	ID        string    `gorm:"type:uuid;primaryKey" json:"id"`
-	Serial    string    `gorm:"size:120;uniqueIndex;not null" json:"serial"`     // 唯一编号
-	Name      string    `gorm:"size:200;not null" json:"name"`                   // 可选：显示名称
-	Status    string    `gorm:"size:20;not null;default:'active'" json:"status"` // 生命周期：active/maintenance/retired...
-	InUse     bool      `gorm:"not null;default:false" json:"inUse"`             // ✅ 冗余列：当前是否被借走
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID         string   `gorm:"type:uuid;primaryKey" json:"id"`
+	Serial     string   `gorm:"size:120;uniqueIndex;not null" json:"serial"`        // 唯一编号
+	Name       string   `gorm:"size:200;not null" json:"name"`                      // 可选：显示名称
+	Status     string   `gorm:"size:20;not null;default:'active'" json:"status"`    // 生命周期：active/maintenance/retired...
+	InUse      bool     `gorm:"not null;default:false" json:"inUse"`                // ✅ 冗余列：当前是否被借走
+	Attributes JSONMap  `gorm:"type:jsonb;not null;default:'{}'" json:"attributes"` // 扩展属性（品牌、型号等）
+	CategoryID *string  `gorm:"type:uuid;index" json:"categoryId,omitempty"`
+	Tags       []string `gorm:"-" json:"tags,omitempty"` // 存在 lsb_item_tags
+
+	HomeLocationID    *string `gorm:"type:uuid;index" json:"homeLocationId,omitempty"`    // 归位处
+	CurrentLocationID *string `gorm:"type:uuid;index" json:"currentLocationId,omitempty"` // 实际放在哪；借出期间为空
+
+	Version    int64      `gorm:"not null;default:1" json:"version"` // 乐观锁：管理员每次编辑 +1，对应 HTTP ETag
+	ArchivedAt *time.Time `gorm:"index" json:"archivedAt,omitempty"` // 归档后不再出现在物品列表，借用历史保留
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
 }
 
 type Loan struct {
@@ -25,11 +35,38 @@ type Loan struct {
 
 	ReturnedAt *time.Time `gorm:"index" json:"returnedAt,omitempty"`
 	ReturnedBy *string    `gorm:"type:uuid" json:"returnedBy,omitempty"`
+	// 归还时放回的位置
+	ReturnLocationID *string `gorm:"type:uuid" json:"returnLocationId,omitempty"`
+	// 成套借出时所属的那次借出
+	KitCheckoutID *string `gorm:"type:uuid;index" json:"kitCheckoutId,omitempty"`
+	// 经转交关闭（转出方）或开始（接收方）的借用对应的转交记录
+	HandoverID *string `gorm:"type:uuid" json:"handoverId,omitempty"`
+	// 借用如何结束：returned / handed_over / lost / stolen / destroyed；未结束为空
+	Outcome string `gorm:"size:20;index" json:"outcome,omitempty"`
+
+	// 借出时生效的计费，之后调价不影响这笔借用；为空的旧借用在关闭时按当时的策略计
+	RateCents    *int64  `json:"rateCents,omitempty"`
+	LateFeeCents *int64  `json:"lateFeeCents,omitempty"`
+	BillingUnit  *string `gorm:"size:10" json:"billingUnit,omitempty"`
 
-	Note      string    `gorm:"size:255" json:"note,omitempty"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	Note         string    `gorm:"size:255" json:"note,omitempty"`
+	RenewalCount int       `gorm:"not null;default:0" json:"renewalCount"` // 已续借次数
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// LoanExtension 每次续借/延期的记录
+type LoanExtension struct {
+	ID         string     `gorm:"type:uuid;primaryKey" json:"id"`
+	LoanID     string     `gorm:"type:uuid;index;not null" json:"loanId"`
+	OldDueAt   *time.Time `json:"oldDueAt,omitempty"`
+	NewDueAt   time.Time  `gorm:"not null" json:"newDueAt"`
+	ExtendedBy string     `gorm:"type:uuid;not null" json:"extendedBy"`
+	ByAdmin    bool       `gorm:"not null;default:false" json:"byAdmin"`
+	Reason     string     `gorm:"size:255" json:"reason,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
 }
 
-func (Item) TableName() string { return ItemTable }
-func (Loan) TableName() string { return LoanTable }
+func (Item) TableName() string          { return ItemTable }
+func (Loan) TableName() string          { return LoanTable }
+func (LoanExtension) TableName() string { return LoanExtensionTable }
@@ -0,0 +1,58 @@
+// models/item_status.go
+package models
+
+import "time"
+
+const ItemStatusChangeTable = "lsb_item_status_changes"
+
+// 物品生命周期状态
+const (
+	ItemStatusActive      = "active"      // 可借
+	ItemStatusMaintenance = "maintenance" // 维修/保养中，暂不可借
+	ItemStatusRetired     = "retired"     // 报废/停用
+
+	// 借出后再也回不来的物品，只能由管理员在关闭借用时宣告（见 LoanLoss）
+	ItemStatusLost      = "lost"      // 丢失
+	ItemStatusStolen    = "stolen"    // 被盗
+	ItemStatusDestroyed = "destroyed" // 损毁无法修复
+)
+
+// ItemTransitions 允许的状态迁移：from → []to
+var ItemTransitions = map[string][]string{
+	ItemStatusActive:      {ItemStatusMaintenance, ItemStatusRetired},
+	ItemStatusMaintenance: {ItemStatusActive, ItemStatusRetired},
+	ItemStatusRetired:     {ItemStatusMaintenance},
+	// 找回后先进保养检查，确认无误再上架
+	ItemStatusLost:      {ItemStatusMaintenance, ItemStatusRetired},
+	ItemStatusStolen:    {ItemStatusMaintenance, ItemStatusRetired},
+	ItemStatusDestroyed: {ItemStatusRetired},
+}
+
+// IsItemStatus 是否为已知状态
+func IsItemStatus(s string) bool {
+	_, ok := ItemTransitions[s]
+	return ok
+}
+
+// CanTransition from → to 是否允许
+func CanTransition(from, to string) bool {
+	for _, s := range ItemTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ItemStatusChange 状态变更历史
+type ItemStatusChange struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ItemID     string    `gorm:"type:uuid;index;not null" json:"itemId"`
+	FromStatus string    `gorm:"size:20;not null" json:"fromStatus"`
+	ToStatus   string    `gorm:"size:20;not null" json:"toStatus"`
+	Reason     string    `gorm:"size:255;not null" json:"reason"`
+	ActorID    string    `gorm:"type:uuid;not null" json:"actorId"`
+	CreatedAt  time.Time `gorm:"index" json:"createdAt"`
+}
+
+func (ItemStatusChange) TableName() string { return ItemStatusChangeTable }
@@ -0,0 +1,41 @@
+// models/kit.go
+package models
+
+import "time"
+
+const KitTable = "lsb_kits"
+const KitItemTable = "lsb_kit_items"
+const KitCheckoutTable = "lsb_kit_checkouts"
+
+// Kit 一套固定搭配的物品（如电钻 + 充电器 + 钻头盒），整套借还
+type Kit struct {
+	ID          string    `gorm:"type:uuid;primaryKey" json:"id"`
+	Name        string    `gorm:"size:120;not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"size:500" json:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// KitItem 成套物品的成员；一件物品可属于多个套装
+type KitItem struct {
+	KitID  string `gorm:"type:uuid;primaryKey" json:"kitId"`
+	ItemID string `gorm:"type:uuid;primaryKey;index" json:"itemId"`
+}
+
+// KitCheckout 一次整套借出；各成员的 Loan.KitCheckoutID 指向它
+type KitCheckout struct {
+	ID         string     `gorm:"type:uuid;primaryKey" json:"id"`
+	KitID      string     `gorm:"type:uuid;index;not null" json:"kitId"`
+	UserID     string     `gorm:"type:uuid;index;not null" json:"userId"`
+	BorrowedAt time.Time  `gorm:"not null" json:"borrowedAt"`
+	DueAt      *time.Time `json:"dueAt,omitempty"`
+	ReturnedAt *time.Time `gorm:"index" json:"returnedAt,omitempty"` // 全部成员归还后才有值
+	ReturnedBy *string    `gorm:"type:uuid" json:"returnedBy,omitempty"`
+	Note       string     `gorm:"size:255" json:"note,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+func (Kit) TableName() string         { return KitTable }
+func (KitItem) TableName() string     { return KitItemTable }
+func (KitCheckout) TableName() string { return KitCheckoutTable }
@@ -0,0 +1,37 @@
+// models/ledger.go
+package models
+
+import "time"
+
+const LedgerEntryTable = "lsb_ledger_entries"
+
+// 账目类型：租金、逾期费为应收；付款、减免为抵扣
+const (
+	LedgerRental  = "rental"
+	LedgerLateFee = "late_fee"
+	LedgerPayment = "payment"
+	LedgerCredit  = "credit"
+)
+
+// IsLedgerCharge 是否为费用（可被减免）
+func IsLedgerCharge(kind string) bool { return kind == LedgerRental || kind == LedgerLateFee }
+
+// LedgerEntry 用户账本的一行，只增不改（减免时另记一条 credit 并在原费用上标记）
+// 余额 = SUM(amount_cents)，正数表示欠款
+type LedgerEntry struct {
+	ID          string  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      string  `gorm:"type:uuid;not null;index" json:"userId"`
+	Kind        string  `gorm:"size:20;not null" json:"kind"`
+	AmountCents int64   `gorm:"not null" json:"amountCents"` // 费用为正，付款 / 减免为负
+	Currency    string  `gorm:"size:3;not null" json:"currency"`
+	LoanID      *string `gorm:"type:uuid;index" json:"loanId,omitempty"`
+	RefID       *string `gorm:"type:uuid" json:"refId,omitempty"` // 减免对应的费用
+	Description string  `gorm:"size:255" json:"description,omitempty"`
+
+	WaivedAt  *time.Time `json:"waivedAt,omitempty"` // 费用已被减免
+	WaivedBy  *string    `gorm:"type:uuid" json:"waivedBy,omitempty"`
+	CreatedBy *string    `gorm:"type:uuid" json:"createdBy,omitempty"` // 手工记账的管理员；自动计费为空
+	CreatedAt time.Time  `gorm:"index" json:"createdAt"`
+}
+
+func (LedgerEntry) TableName() string { return LedgerEntryTable }
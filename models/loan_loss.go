@@ -0,0 +1,34 @@
+// models/loan_loss.go
+package models
+
+import "time"
+
+const LoanLossTable = "lsb_loan_losses"
+
+// 借用结束方式
+const (
+	LoanOutcomeReturned   = "returned"
+	LoanOutcomeHandedOver = "handed_over"
+	LoanOutcomeLost       = ItemStatusLost
+	LoanOutcomeStolen     = ItemStatusStolen
+	LoanOutcomeDestroyed  = ItemStatusDestroyed
+)
+
+// IsLossOutcome 是否为宣告损失的结束方式（与物品状态同名）
+func IsLossOutcome(s string) bool {
+	return s == LoanOutcomeLost || s == LoanOutcomeStolen || s == LoanOutcomeDestroyed
+}
+
+// LoanLoss 管理员宣告借出物品丢失 / 被盗 / 损毁时的记录；借用人对该物品负责
+type LoanLoss struct {
+	ID         string    `gorm:"type:uuid;primaryKey" json:"id"`
+	LoanID     string    `gorm:"type:uuid;not null;uniqueIndex" json:"loanId"`
+	ItemID     string    `gorm:"type:uuid;not null;index" json:"itemId"`
+	UserID     string    `gorm:"type:uuid;not null;index" json:"userId"` // 责任人（借用人）
+	Outcome    string    `gorm:"size:20;not null" json:"outcome"`
+	Note       string    `gorm:"size:255" json:"note,omitempty"`
+	DeclaredBy string    `gorm:"type:uuid;not null" json:"declaredBy"`
+	CreatedAt  time.Time `gorm:"index" json:"createdAt"`
+}
+
+func (LoanLoss) TableName() string { return LoanLossTable }
@@ -0,0 +1,27 @@
+// models/loan_notification.go
+package models
+
+import "time"
+
+const LoanNotificationTable = "lsb_loan_notifications"
+
+// 提醒种类
+const (
+	NoticeDueSoon    = "due_soon"   // 即将到期，发给借用人
+	NoticeOverdue    = "overdue"    // 已逾期，发给借用人
+	NoticeEscalation = "escalation" // 逾期超过 N 天，发给管理员
+)
+
+// LoanNotification 已发送的提醒；(loan_id, kind, due_at, recipient) 唯一，保证不重复发送
+// 续借改了 due_at 后会重新提醒
+type LoanNotification struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	LoanID    string    `gorm:"type:uuid;not null;uniqueIndex:idx_loan_notice_once" json:"loanId"`
+	Kind      string    `gorm:"size:20;not null;uniqueIndex:idx_loan_notice_once" json:"kind"`
+	DueAt     time.Time `gorm:"not null;uniqueIndex:idx_loan_notice_once" json:"dueAt"`
+	Recipient string    `gorm:"size:255;not null;uniqueIndex:idx_loan_notice_once" json:"recipient"`
+	Channel   string    `gorm:"size:20;not null;default:'email'" json:"channel"`
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+}
+
+func (LoanNotification) TableName() string { return LoanNotificationTable }
@@ -0,0 +1,60 @@
+// models/loan_policy.go
+package models
+
+import "time"
+
+const LoanPolicyTable = "lsb_loan_policies"
+const LoanBlackoutTable = "lsb_loan_blackouts"
+
+// 计费单位
+const (
+	BillingUnitHour = "hour"
+	BillingUnitDay  = "day"
+)
+
+// 策略作用域：全局一条，分类级（含子分类）覆盖全局，物品级覆盖分类
+const (
+	PolicyScopeGlobal   = "global"
+	PolicyScopeCategory = "category"
+	PolicyScopeItem     = "item"
+)
+
+// LoanPolicy 借用规则；指针字段为空表示“不限制 / 沿用上一级”
+type LoanPolicy struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	Scope   string `gorm:"size:20;not null;uniqueIndex:idx_loan_policy_scope" json:"scope"`
+	ScopeID string `gorm:"size:64;not null;default:'';uniqueIndex:idx_loan_policy_scope" json:"scopeId,omitempty"` // 物品/分类 ID；全局为空
+
+	MaxOpenLoans     *int `json:"maxOpenLoans,omitempty"`     // 每个用户同时未归还的上限
+	DefaultLoanHours *int `json:"defaultLoanHours,omitempty"` // 未指定 dueAt 时的借期
+	MaxLoanHours     *int `json:"maxLoanHours,omitempty"`     // dueAt 距借出时刻的上限
+
+	RequiresApproval *bool `json:"requiresApproval,omitempty"` // 借出需管理员审批（贵重 / 危险工具）
+
+	// 计费：金额以分为单位，按计费单位（hour / day）向上取整；0 表示免费
+	RateCents    *int64  `json:"rateCents,omitempty"`    // 每计费单位租金
+	LateFeeCents *int64  `json:"lateFeeCents,omitempty"` // 超过 dueAt 后每计费单位逾期费
+	BillingUnit  *string `gorm:"size:10" json:"billingUnit,omitempty"`
+
+	// 允许借出的时段（本地时间 "HH:MM"），两者都设置时生效；From > Until 表示跨午夜
+	BorrowFrom  *string `gorm:"size:5" json:"borrowFrom,omitempty"`
+	BorrowUntil *string `gorm:"size:5" json:"borrowUntil,omitempty"`
+	Timezone    string  `gorm:"size:64" json:"timezone,omitempty"` // IANA 时区，空 = UTC
+
+	UpdatedBy string    `gorm:"type:uuid" json:"updatedBy,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// LoanBlackout 禁止借出的时间段（节假日、盘点等），对所有物品生效
+type LoanBlackout struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	StartsAt  time.Time `gorm:"index;not null" json:"startsAt"`
+	EndsAt    time.Time `gorm:"index;not null" json:"endsAt"`
+	Reason    string    `gorm:"size:255" json:"reason,omitempty"`
+	CreatedBy string    `gorm:"type:uuid" json:"createdBy,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (LoanPolicy) TableName() string   { return LoanPolicyTable }
+func (LoanBlackout) TableName() string { return LoanBlackoutTable }
@@ -0,0 +1,42 @@
+// models/location.go
+package models
+
+import "time"
+
+const LocationTable = "lsb_locations"
+
+// 存放位置层级：场地 → 房间 → 货架 → 货位（货位也可直接挂在房间下）
+const (
+	LocationSite  = "site"
+	LocationRoom  = "room"
+	LocationShelf = "shelf"
+	LocationBin   = "bin"
+)
+
+// LocationRank 层级深度，子位置必须比父位置深；未知类型返回 -1
+func LocationRank(kind string) int {
+	switch kind {
+	case LocationSite:
+		return 0
+	case LocationRoom:
+		return 1
+	case LocationShelf:
+		return 2
+	case LocationBin:
+		return 3
+	}
+	return -1
+}
+
+// Location 存放位置；同一父级下名称唯一（Migrate 中建索引）
+type Location struct {
+	ID        string    `gorm:"type:uuid;primaryKey" json:"id"`
+	ParentID  *string   `gorm:"type:uuid;index" json:"parentId,omitempty"`
+	Kind      string    `gorm:"size:16;not null" json:"kind"`
+	Name      string    `gorm:"size:120;not null" json:"name"`
+	Note      string    `gorm:"size:255" json:"note,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (Location) TableName() string { return LocationTable }
@@ -0,0 +1,65 @@
+// models/maintenance.go
+package models
+
+import "time"
+
+const MaintenancePlanTable = "lsb_maintenance_plans"
+const WorkOrderTable = "lsb_work_orders"
+
+// 保养计划类型
+const (
+	PlanKindCalibration = "calibration"
+	PlanKindService     = "service"
+)
+
+// 工单状态：open → in_progress → done（open 也可直接 done）
+const (
+	WorkOrderOpen       = "open"
+	WorkOrderInProgress = "in_progress"
+	WorkOrderDone       = "done"
+)
+
+// 工单来源
+const (
+	WorkOrderScheduled = "scheduled" // 来自保养计划
+	WorkOrderFault     = "fault"     // 借用人报修
+	WorkOrderManual    = "manual"    // 管理员手工创建
+)
+
+// MaintenancePlan 周期性校准/保养：按天数或按使用小时（借出时长累计），任一到期即算到期
+type MaintenancePlan struct {
+	ID                 string     `gorm:"type:uuid;primaryKey" json:"id"`
+	ItemID             string     `gorm:"type:uuid;index;not null" json:"itemId"`
+	Kind               string     `gorm:"size:20;not null;default:'service'" json:"kind"` // calibration/service
+	Title              string     `gorm:"size:200;not null" json:"title"`
+	IntervalDays       *int       `json:"intervalDays,omitempty"`
+	IntervalUsageHours *int       `json:"intervalUsageHours,omitempty"`
+	BlocksBorrowing    bool       `gorm:"not null;default:false" json:"blocksBorrowing"` // 到期后禁止借出（如校准过期）
+	LastDoneAt         *time.Time `json:"lastDoneAt,omitempty"`
+	NextDueAt          *time.Time `gorm:"index" json:"nextDueAt,omitempty"` // 按天数算出的下次到期
+	Active             bool       `gorm:"not null;default:true" json:"active"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	UpdatedAt          time.Time  `json:"updatedAt"`
+}
+
+// WorkOrder 维修/保养工单
+type WorkOrder struct {
+	ID          string     `gorm:"type:uuid;primaryKey" json:"id"`
+	ItemID      string     `gorm:"type:uuid;index;not null" json:"itemId"`
+	PlanID      *string    `gorm:"type:uuid;index" json:"planId,omitempty"`
+	LoanID      *string    `gorm:"type:uuid" json:"loanId,omitempty"` // 报修时所在的借用
+	Source      string     `gorm:"size:20;not null" json:"source"`    // scheduled/fault/manual
+	Status      string     `gorm:"size:20;not null;default:'open';index" json:"status"`
+	Title       string     `gorm:"size:200;not null" json:"title"`
+	Description string     `gorm:"type:text" json:"description,omitempty"`
+	Resolution  string     `gorm:"type:text" json:"resolution,omitempty"`
+	ReportedBy  string     `gorm:"type:uuid;not null" json:"reportedBy"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	CompletedBy *string    `gorm:"type:uuid" json:"completedBy,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+func (MaintenancePlan) TableName() string { return MaintenancePlanTable }
+func (WorkOrder) TableName() string       { return WorkOrderTable }
@@ -0,0 +1,32 @@
+// models/reservation.go
+package models
+
+import "time"
+
+const ReservationTable = "lsb_reservations"
+
+// 预约状态
+const (
+	ReservationConfirmed = "confirmed"
+	ReservationCancelled = "cancelled"
+)
+
+// Reservation 某用户在 [StartAt, EndAt) 时间段内预定某件物品
+// 重叠由 Postgres 排他约束保证（见 db.Migrate）
+type Reservation struct {
+	ID      string    `gorm:"type:uuid;primaryKey" json:"id"`
+	ItemID  string    `gorm:"type:uuid;index;not null" json:"itemId"`
+	UserID  string    `gorm:"type:uuid;index;not null" json:"userId"`
+	StartAt time.Time `gorm:"index;not null" json:"startAt"`
+	EndAt   time.Time `gorm:"not null" json:"endAt"`
+	Status  string    `gorm:"size:20;not null;default:'confirmed'" json:"status"` // confirmed/cancelled
+
+	Note        string     `gorm:"size:255" json:"note,omitempty"`
+	CancelledAt *time.Time `json:"cancelledAt,omitempty"`
+	CancelledBy *string    `gorm:"type:uuid" json:"cancelledBy,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (Reservation) TableName() string { return ReservationTable }
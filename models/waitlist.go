@@ -0,0 +1,32 @@
+// models/waitlist.go
+package models
+
+import "time"
+
+const WaitlistTable = "lsb_waitlist"
+
+// 排队状态：waiting → offered（限时认领）→ fulfilled / expired；随时可 cancelled
+const (
+	WaitlistWaiting   = "waiting"
+	WaitlistOffered   = "offered"
+	WaitlistFulfilled = "fulfilled"
+	WaitlistExpired   = "expired"
+	WaitlistCancelled = "cancelled"
+)
+
+// WaitlistEntry 某用户在某物品上的排队记录，按 CreatedAt 先后排序
+type WaitlistEntry struct {
+	ID     string `gorm:"type:uuid;primaryKey" json:"id"`
+	ItemID string `gorm:"type:uuid;index;not null" json:"itemId"`
+	UserID string `gorm:"type:uuid;index;not null" json:"userId"`
+	Status string `gorm:"size:20;not null;default:'waiting'" json:"status"`
+
+	OfferedAt      *time.Time `json:"offeredAt,omitempty"`
+	ClaimExpiresAt *time.Time `gorm:"index" json:"claimExpiresAt,omitempty"` // 认领截止，过期后顺延给下一位
+	LoanID         *string    `gorm:"type:uuid" json:"loanId,omitempty"`     // 认领成功后对应的借用
+
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (WaitlistEntry) TableName() string { return WaitlistTable }
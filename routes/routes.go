@@ -18,6 +18,22 @@ func RegisterRoutes(r *gin.Engine, a *app.App) {
 	itemCtl := controllers.NewItemController(s)
 	inviteCtl := controllers.GetInviteController(s)
 	lc := controllers.NewLockController(s)
+	resCtl := controllers.NewReservationController(s)
+	waitCtl := controllers.NewWaitlistController(s)
+	policyCtl := controllers.NewPolicyController(s)
+	mtCtl := controllers.NewMaintenanceController(s)
+	attCtl := controllers.NewAttachmentController(s)
+	scanCtl := controllers.NewScanController(s)
+	expCtl := controllers.NewExportController(s)
+	catCtl := controllers.NewCategoryController(s)
+	searchCtl := controllers.NewSearchController(s)
+	locCtl := controllers.NewLocationController(s)
+	kitCtl := controllers.NewKitController(s)
+	condCtl := controllers.NewConditionController(s)
+	cartCtl := controllers.NewCartController(s)
+	hoCtl := controllers.NewHandoverController(s)
+	brCtl := controllers.NewBorrowRequestController(s)
+	billCtl := controllers.NewBillingController(s)
 	// 复用的中间件
 	authMW := app.AuthRequired(s.AppSess, s.Repo, a.Config)
 	adminMW := app.AdminOnly(a.Config, s.Repo)
@@ -112,21 +128,209 @@ func RegisterRoutes(r *gin.Engine, a *app.App) {
 
 	{
 		itemsAdmin.POST("", itemCtl.CreateItem)
-		itemsAdmin.POST("/borrow", itemCtl.AdminBorrow)  // 管理员代借
-		itemsAdmin.POST("/return", itemCtl.AdminReturn)  // 管理员代还
-		itemsAdmin.GET("/items", itemCtl.ListItemsAdmin) // ?q=&status=&page=&size=)
+		itemsAdmin.POST("/items/import", itemCtl.ImportItems) // ?dryRun=true  multipart: file（CSV）
+		itemsAdmin.POST("/loans/import", itemCtl.ImportLoans) // 历史借用记录，同上
+		itemsAdmin.POST("/borrow", itemCtl.AdminBorrow)       // 管理员代借
+		itemsAdmin.POST("/return", itemCtl.AdminReturn)       // 管理员代还
+		itemsAdmin.GET("/items", itemCtl.ListItemsAdmin)      // ?q=&status=&itemStatus=&archived=&locationId=&attr.<key>=&attrMin.<key>=&attrMax.<key>=&page=&size=
+		itemsAdmin.GET("/reservations", resCtl.ListAdmin)     // ?userId=&itemId=&status=&from=&to=
+		itemsAdmin.GET("/items/:id/waitlist", waitCtl.ListItem)
+		itemsAdmin.PATCH("/items/:id", itemCtl.UpdateItem) // 编辑/归档/删除需带 If-Match（GET /api/items/:id 返回的 ETag）
+		itemsAdmin.POST("/items/:id/archive", itemCtl.ArchiveItem)
+		itemsAdmin.POST("/items/:id/unarchive", itemCtl.UnarchiveItem)
+		itemsAdmin.DELETE("/items/:id", itemCtl.DeleteItem)
+		itemsAdmin.POST("/items/:id/status", itemCtl.ChangeStatus) // 生命周期迁移
+		itemsAdmin.GET("/items/:id/status-history", itemCtl.StatusHistory)
+		itemsAdmin.POST("/loans/:loanId/extend", itemCtl.AdminExtend)   // 管理员延期
+		itemsAdmin.POST("/loans/:loanId/declare", itemCtl.DeclareLoss)  // {outcome: lost|stolen|destroyed, note?}
+		itemsAdmin.GET("/loan-losses", itemCtl.ListLossesAdmin)         // ?userId=&itemId=&outcome=
+		itemsAdmin.GET("/notifications", itemCtl.ListLoanNotifications) // ?loanId=
+
+		// 借用策略（改完即时生效）
+		itemsAdmin.GET("/policies", policyCtl.List)
+		itemsAdmin.PUT("/policies/global", policyCtl.PutGlobal)
+		itemsAdmin.PUT("/policies/items/:id", policyCtl.PutItem)
+		itemsAdmin.DELETE("/policies/items/:id", policyCtl.DeleteItem)
+		itemsAdmin.PUT("/policies/categories/:id", policyCtl.PutCategory)
+		itemsAdmin.DELETE("/policies/categories/:id", policyCtl.DeleteCategory)
+		itemsAdmin.POST("/policies/blackouts", policyCtl.CreateBlackout)
+		itemsAdmin.DELETE("/policies/blackouts/:id", policyCtl.DeleteBlackout)
+
+		// 保养/校准计划与工单
+		itemsAdmin.POST("/items/:id/maintenance-plans", mtCtl.CreatePlan)
+		itemsAdmin.GET("/items/:id/maintenance-plans", mtCtl.ListPlans)
+		itemsAdmin.PUT("/maintenance-plans/:planId", mtCtl.UpdatePlan)
+		itemsAdmin.DELETE("/maintenance-plans/:planId", mtCtl.DeletePlan)
+		itemsAdmin.GET("/maintenance/upcoming", mtCtl.Upcoming) // ?days=
+		itemsAdmin.POST("/work-orders", mtCtl.CreateWorkOrder)
+		itemsAdmin.GET("/work-orders", mtCtl.ListWorkOrders) // ?itemId=&status=&source=
+		itemsAdmin.POST("/work-orders/:id/status", mtCtl.UpdateWorkOrderStatus)
+
+		// 附件（手册/照片/证书）
+		itemsAdmin.POST("/items/:id/attachments", attCtl.UploadItemAttachment) // multipart: file, kind
+		itemsAdmin.DELETE("/attachments/:id", attCtl.Delete)
+
+		// 分类与标签
+		itemsAdmin.POST("/categories", catCtl.Create)
+		itemsAdmin.PUT("/categories/:id", catCtl.Update)
+		itemsAdmin.DELETE("/categories/:id", catCtl.Delete)
+		itemsAdmin.PUT("/items/:id/category", catCtl.SetItemCategory)
+		itemsAdmin.PUT("/items/:id/tags", catCtl.SetItemTags)
+		itemsAdmin.PUT("/items/:id/attributes", catCtl.SetItemAttributes)
+		itemsAdmin.POST("/categories/:id/attributes", catCtl.CreateAttribute)
+		itemsAdmin.PUT("/category-attributes/:id", catCtl.UpdateAttribute)
+		itemsAdmin.DELETE("/category-attributes/:id", catCtl.DeleteAttribute)
+
+		// 存放位置：场地 → 房间 → 货架/货位
+		itemsAdmin.POST("/locations", locCtl.Create)
+		itemsAdmin.PUT("/locations/:id", locCtl.Update)
+		itemsAdmin.DELETE("/locations/:id", locCtl.Delete)
+		itemsAdmin.GET("/locations/:id/items", locCtl.Items) // ?scope=current|home&page=&size=
+		itemsAdmin.PUT("/items/:id/location", locCtl.SetItemLocation)
+
+		// 成套物品
+		itemsAdmin.POST("/kits", kitCtl.Create)
+		itemsAdmin.PUT("/kits/:id", kitCtl.Update)
+		itemsAdmin.DELETE("/kits/:id", kitCtl.Delete)
+		itemsAdmin.GET("/kit-checkouts", kitCtl.ListAdmin) // ?userId=&open=true
+
+		// 借还配件清单（分类 / 物品）与状况记录
+		itemsAdmin.GET("/checklist-items", condCtl.ListChecklistItems) // ?scope=category|item&scopeId=
+		itemsAdmin.POST("/checklist-items", condCtl.CreateChecklistItem)
+		itemsAdmin.PUT("/checklist-items/:id", condCtl.UpdateChecklistItem)
+		itemsAdmin.DELETE("/checklist-items/:id", condCtl.DeleteChecklistItem)
+		itemsAdmin.GET("/condition-reports", condCtl.ListReports) // ?itemId=&flagged=true
+		itemsAdmin.GET("/handovers", hoCtl.ListAdmin)             // ?userId=&itemId=&status=
+
+		// 需审批物品的借用申请
+		itemsAdmin.GET("/borrow-requests", brCtl.ListAdmin)            // ?status=pending|all|...&userId=&itemId=
+		itemsAdmin.POST("/borrow-requests/:id/approve", brCtl.Approve) // {reason?, dueAt?}
+		itemsAdmin.POST("/borrow-requests/:id/reject", brCtl.Reject)   // {reason}
+
+		// 计费：价格在借用策略里配置，借用关闭时自动记账
+		itemsAdmin.GET("/billing/ledger", billCtl.Ledger)                          // ?userId=&kind=&from=&to=
+		itemsAdmin.GET("/billing/users/:id/invoices/:period", billCtl.UserInvoice) // period=YYYY-MM ?format=json|pdf
+		itemsAdmin.POST("/billing/payments", billCtl.RecordPayment)                // {userId, amountCents, method?, note?}
+		itemsAdmin.POST("/billing/entries/:id/waive", billCtl.Waive)               // {reason}
+
+		// 扫码标识（条码 / NFC / 别名）
+		itemsAdmin.POST("/items/:id/identifiers", scanCtl.AddIdentifier)
+		itemsAdmin.GET("/items/:id/identifiers", scanCtl.ListIdentifiers)
+		itemsAdmin.DELETE("/identifiers/:id", scanCtl.DeleteIdentifier)
+
+		// 导出（format=csv|xlsx，过滤条件同对应列表接口）
+		itemsAdmin.GET("/export/items", expCtl.Items)
+		itemsAdmin.GET("/export/loans", expCtl.Loans)
+		itemsAdmin.GET("/export/users", expCtl.Users)
+
+		// 标签打印：A4 PDF（过滤条件同 /items）
+		itemsAdmin.GET("/labels", itemCtl.LabelSheet) // ?q=&status=&itemStatus=
 
 	}
 
 	// 用户：浏览/借/还/记录
 	items := r.Group("/api/items", authMW, seenMW)
 	{
-		items.GET("", itemCtl.ListItems)
+		items.GET("", itemCtl.ListItems) // ?categoryId=&tag=
 		items.POST("/:id/borrow", itemCtl.Borrow)
 		items.POST("/loans/:loanId/return", itemCtl.Return)
 		// items.GET("/loans", itemCtl.ListLoans) // ?status=open|returned&userId=&itemId=
 		items.GET("/loans/open", itemCtl.ListMyOpenLoans)
+		items.GET("/loans/losses", itemCtl.ListMyLosses)
+		items.POST("/loans/:loanId/renew", itemCtl.Renew)
+		items.GET("/loans/:loanId/extensions", itemCtl.ListLoanExtensions)
+		items.POST("/loans/:loanId/fault", mtCtl.ReportFault)       // 报修
+		items.POST("/loans/:loanId/photos", attCtl.UploadLoanPhoto) // multipart: file, stage=checkout|return
+		items.GET("/loans/:loanId/photos", attCtl.ListLoanPhotos)
+		items.GET("/loans/:loanId/condition", condCtl.LoanConditions)
+		items.POST("/loans/:loanId/condition", condCtl.RecordCondition) // {stage, grade, damage?, checklist}
+		items.GET("/:id/checklist", condCtl.ItemChecklist)
+		items.POST("/loans/:loanId/handover", hoCtl.Start) // {toUsername, note?}
+
+		// 预约 / 可用性时间线
+		items.POST("/:id/reservations", resCtl.Create)
+		items.GET("/:id/availability", resCtl.Availability) // ?from=&to=
+		items.GET("/:id/policy", policyCtl.Effective)
+		items.GET("/:id", itemCtl.GetItem)     // 详情 + 附件
+		items.GET("/:id/label", itemCtl.Label) // ?format=png|svg&size=
+
+		// 排队（物品被借出时）
+		items.GET("/waitlist", waitCtl.ListMine)
+		items.POST("/:id/waitlist", waitCtl.Join)
+		items.DELETE("/:id/waitlist", waitCtl.Leave)
+	}
 
+	// 成套借还：全部成员在一个事务里借出 / 归还
+	kits := r.Group("/api/kits", authMW, seenMW)
+	{
+		kits.GET("", kitCtl.List)
+		kits.GET("/checkouts", kitCtl.ListMine) // ?open=true
+		kits.POST("/checkouts/:checkoutId/return", kitCtl.Return)
+		kits.GET("/:id", kitCtl.Get)
+		kits.POST("/:id/borrow", kitCtl.Borrow)
+	}
+
+	// 借用转交：接收人确认后旧借用关闭、新借用开始
+	handovers := r.Group("/api/handovers", authMW, seenMW)
+	{
+		handovers.GET("", hoCtl.ListMine) // ?status=pending
+		handovers.POST("/:id/accept", hoCtl.Accept)
+		handovers.POST("/:id/decline", hoCtl.Decline)
+		handovers.POST("/:id/cancel", hoCtl.Cancel)
+	}
+
+	// 借用申请：借需审批的物品时自动提交，批准后才借出
+	borrowReqs := r.Group("/api/borrow-requests", authMW, seenMW)
+	{
+		borrowReqs.GET("", brCtl.ListMine) // ?status=pending
+		borrowReqs.POST("/:id/cancel", brCtl.Cancel)
+	}
+
+	// 我的账本与账单
+	billing := r.Group("/api/billing", authMW, seenMW)
+	{
+		billing.GET("/ledger", billCtl.MyLedger)            // ?kind=&from=&to=
+		billing.GET("/invoices/:period", billCtl.MyInvoice) // period=YYYY-MM ?format=json|pdf
+	}
+
+	// 购物车：放入即临时占用，结账时一个事务全部借出
+	cartGrp := r.Group("/api/cart", authMW, seenMW)
+	{
+		cartGrp.GET("", cartCtl.Get)
+		cartGrp.DELETE("", cartCtl.Clear)
+		cartGrp.POST("/items", cartCtl.Add) // {itemId}
+		cartGrp.DELETE("/items/:itemId", cartCtl.Remove)
+		cartGrp.POST("/checkout", cartCtl.Checkout) // {dueAt?, note?}
+	}
+
+	// 我的预约
+	reservations := r.Group("/api/reservations", authMW, seenMW)
+	{
+		reservations.GET("", resCtl.ListMine) // ?itemId=&status=&from=&to=
+		reservations.DELETE("/:id", resCtl.Cancel)
+	}
+	// 分类树 / 标签 / 位置 / 搜索联想（登录即可浏览）
+	catalog := r.Group("/api", authMW)
+	{
+		catalog.GET("/categories", catCtl.List)
+		catalog.GET("/categories/:id/attributes", catCtl.AttributeSchema)
+		catalog.GET("/tags", catCtl.ListTags) // ?prefix=
+		catalog.GET("/locations", locCtl.List)
+		catalog.GET("/search/suggest", searchCtl.Suggest) // ?q=&limit=  物品；管理员另含用户
+	}
+
+	// 扫码借还：code 可以是标签深链接、编号、条码或 NFC UID
+	scan := r.Group("/api/scan", authMW, seenMW)
+	{
+		scan.GET("/resolve", scanCtl.Resolve) // ?code=
+		scan.POST("", scanCtl.Toggle)
+	}
+
+	// 附件下载 / 缩略图
+	attachments := r.Group("/api/attachments", authMW)
+	{
+		attachments.GET("/:id", attCtl.Download)
+		attachments.GET("/:id/thumbnail", attCtl.Thumbnail)
 	}
 	//  unlock
 	unlock := r.Group("/api/unlock", authMW)
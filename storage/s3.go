@@ -0,0 +1,72 @@
+// storage/s3.go
+package storage
+
+import (
+	"context"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config S3 兼容存储（AWS S3、MinIO 等）；本地开发可用 MinIO 容器代替
+type S3Config struct {
+	Endpoint  string // e.g. 127.0.0.1:9000 / s3.amazonaws.com
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	UseSSL    bool
+}
+
+type S3Store struct {
+	cli    *minio.Client
+	bucket string
+}
+
+func NewS3Store(ctx context.Context, cfg S3Config) (*S3Store, error) {
+	cli, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// bucket 不存在则创建（MinIO 本地环境常见）
+	ok, err := cli.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := cli.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+			return nil, err
+		}
+	}
+	return &S3Store{cli: cli, bucket: cfg.Bucket}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.cli.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := s.cli.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject 是惰性的，Stat 一次把 404 提前暴露出来
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	return s.cli.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,45 @@
+// storage/storage.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore 文件存储抽象：本地目录或 S3 兼容对象存储
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// FromEnv 按 BLOB_BACKEND 选择实现：local（默认）/ s3
+func FromEnv(ctx context.Context) (BlobStore, error) {
+	get := func(k, d string) string {
+		if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+			return v
+		}
+		return d
+	}
+	switch backend := get("BLOB_BACKEND", "local"); backend {
+	case "local":
+		return NewLocalStore(get("BLOB_LOCAL_DIR", "./data/blobs"))
+	case "s3":
+		return NewS3Store(ctx, S3Config{
+			Endpoint:  get("S3_ENDPOINT", "127.0.0.1:9000"),
+			AccessKey: get("S3_ACCESS_KEY", ""),
+			SecretKey: get("S3_SECRET_KEY", ""),
+			Bucket:    get("S3_BUCKET", "rent-tool"),
+			Region:    get("S3_REGION", ""),
+			UseSSL:    get("S3_USE_SSL", "false") == "true",
+		})
+	default:
+		return nil, fmt.Errorf("unknown BLOB_BACKEND %q", backend)
+	}
+}
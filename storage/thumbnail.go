@@ -0,0 +1,71 @@
+// storage/thumbnail.go
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+
+	// 注册解码器
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxImagePixels 解码前按文件头声明的尺寸拦截，防止小文件声明超大尺寸把内存撑爆
+const MaxImagePixels = 40_000_000
+
+var ErrImageTooLarge = errors.New("image dimensions too large")
+
+// CheckImageSize 只读文件头（DecodeConfig），像素数超过 MaxImagePixels 返回 ErrImageTooLarge；
+// 无法识别的格式交给后续解码处理
+func CheckImageSize(data []byte) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return fmt.Errorf("%w: %dx%d exceeds %d pixels", ErrImageTooLarge, cfg.Width, cfg.Height, MaxImagePixels)
+	}
+	return nil
+}
+
+// MakeThumbnail 等比缩放到最长边不超过 maxSide，输出 JPEG
+func MakeThumbnail(data []byte, maxSide int) ([]byte, error) {
+	if err := CheckImageSize(data); err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			h = h * maxSide / w
+			w = maxSide
+		} else {
+			w = w * maxSide / h
+			h = maxSide
+		}
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
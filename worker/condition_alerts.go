@@ -0,0 +1,88 @@
+// worker/condition_alerts.go
+package worker
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/db"
+	"Gin_postgres_redis_rent_tool/mailer"
+)
+
+// sweepConditionAlerts 归还时有损坏或缺配件的记录 → 通知所有管理员，每条只通知一次
+func (w *Runner) sweepConditionAlerts(ctx context.Context) error {
+	rows, err := w.Repo.PendingConditionAlerts(ctx)
+	if err != nil || len(rows) == 0 {
+		return err
+	}
+	admins, err := w.adminRecipients(ctx)
+	if err != nil {
+		return err
+	}
+	conf := mailer.Load()
+	sent := 0
+	for _, row := range rows {
+		ok, err := w.Repo.MarkConditionNotified(ctx, row.ID, time.Now().UTC())
+		if err != nil {
+			log.Printf("[worker condition] mark %s: %v", row.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		subject := fmt.Sprintf("%s: %s returned with problems", conf.AppName, row.Serial)
+		body := conditionAlertBody(row)
+
+		// 未配置 SMTP → 开发模式：打印即可
+		if !conf.Configured() {
+			log.Printf("[DEV] condition alert for %s → %s: %s", row.Serial, strings.Join(admins, ", "), subject)
+			sent++
+			continue
+		}
+		delivered := 0
+		for _, to := range admins {
+			if err := conf.Send(to, subject, body); err != nil {
+				log.Printf("[worker condition] send to %s: %v", to, err)
+				continue
+			}
+			delivered++
+		}
+		// 一封都没发出去则撤回，下一轮重试
+		if delivered == 0 && len(admins) > 0 {
+			_ = w.Repo.UnmarkConditionNotified(ctx, row.ID)
+			continue
+		}
+		sent++
+	}
+	if sent > 0 {
+		log.Printf("[worker condition] alerted %d report(s)", sent)
+	}
+	return nil
+}
+
+func conditionAlertBody(row db.ConditionReportRow) string {
+	var items []string
+	if row.Damage != "" {
+		items = append(items, "<li>Damage: "+html.EscapeString(row.Damage)+"</li>")
+	}
+	for _, m := range row.Missing() {
+		items = append(items, "<li>Missing: "+html.EscapeString(m)+"</li>")
+	}
+	return fmt.Sprintf(`
+<div style="font-family:Arial,sans-serif; font-size:14px; color:#222">
+  <p>Hello,</p>
+  <p>The tool <b>%s</b> (serial %s) borrowed by <b>%s</b> was returned in condition <b>%s</b>.</p>
+  <ul>%s</ul>
+  <p>Please inspect it before it is lent out again.</p>
+  <p>Reported by %s at %s</p>
+  <hr/>
+  <p style="color:#666">This is an automated notification.</p>
+</div>
+`, html.EscapeString(row.Name), html.EscapeString(row.Serial), html.EscapeString(row.BorrowerUsername),
+		html.EscapeString(row.Grade), strings.Join(items, ""),
+		html.EscapeString(row.ReporterUsername), row.CreatedAt.Format(time.RFC1123))
+}
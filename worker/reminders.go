@@ -0,0 +1,125 @@
+// worker/reminders.go
+package worker
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/db"
+	"Gin_postgres_redis_rent_tool/mailer"
+	"Gin_postgres_redis_rent_tool/models"
+)
+
+// sweepReminders 一轮提醒：即将到期 → 已逾期 → 逾期升级给管理员
+func (w *Runner) sweepReminders(ctx context.Context) error {
+	now := time.Now().UTC()
+	conf := mailer.Load()
+	sent := 0
+
+	// 1) 即将到期
+	rows, err := w.Repo.ListDueLoans(ctx, models.NoticeDueSoon, now, now.Add(w.Cfg.ReminderDueSoon))
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		subject := fmt.Sprintf("%s: %s is due soon", conf.AppName, row.Name)
+		body := reminderBody(row, fmt.Sprintf("is due back on <b>%s</b>.", row.DueAt.Format(time.RFC1123)))
+		sent += w.notify(ctx, conf, row, models.NoticeDueSoon, row.BorrowerUsername, subject, body)
+	}
+
+	// 2) 已逾期
+	rows, err = w.Repo.ListDueLoans(ctx, models.NoticeOverdue, time.Unix(0, 0), now)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		subject := fmt.Sprintf("%s: %s is overdue", conf.AppName, row.Name)
+		body := reminderBody(row, fmt.Sprintf("was due back on <b>%s</b> and is now overdue. Please return it as soon as possible.", row.DueAt.Format(time.RFC1123)))
+		sent += w.notify(ctx, conf, row, models.NoticeOverdue, row.BorrowerUsername, subject, body)
+	}
+
+	// 3) 逾期超过 N 天 → 通知所有管理员；按收件人去重，已收到的由 notify 跳过
+	admins, err := w.adminRecipients(ctx)
+	if err != nil {
+		return err
+	}
+	if len(admins) > 0 {
+		rows, err = w.Repo.ListDueLoans(ctx, models.NoticeEscalation, time.Unix(0, 0), now.Add(-w.Cfg.ReminderEscalateAfter), admins...)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			days := int(now.Sub(row.DueAt).Hours() / 24)
+			subject := fmt.Sprintf("%s: %s overdue by %d day(s)", conf.AppName, row.Serial, days)
+			body := reminderBody(row, fmt.Sprintf("borrowed by <b>%s</b> (%s) has been overdue for %d day(s) (due %s).",
+				html.EscapeString(row.BorrowerDisplayName), html.EscapeString(row.BorrowerUsername), days, row.DueAt.Format(time.RFC1123)))
+			for _, to := range admins {
+				sent += w.notify(ctx, conf, row, models.NoticeEscalation, to, subject, body)
+			}
+		}
+	}
+
+	if sent > 0 {
+		log.Printf("[worker reminders] sent %d notification(s)", sent)
+	}
+	return nil
+}
+
+// notify 先记录再发送，保证同一提醒只发一次；发送失败则撤回记录等下一轮
+func (w *Runner) notify(ctx context.Context, conf mailer.Conf, row db.DueLoanRow, kind, to, subject, body string) int {
+	n := &models.LoanNotification{LoanID: row.LoanID, Kind: kind, DueAt: row.DueAt, Recipient: strings.ToLower(to), Channel: "email"}
+	ok, err := w.Repo.RecordLoanNotification(ctx, n)
+	if err != nil {
+		log.Printf("[worker reminders] record %s for loan %s: %v", kind, row.LoanID, err)
+		return 0
+	}
+	if !ok {
+		return 0
+	}
+
+	// 未配置 SMTP → 开发模式：打印即可
+	if !conf.Configured() {
+		log.Printf("[DEV] %s reminder for loan %s → %s: %s", kind, row.LoanID, to, subject)
+		return 1
+	}
+	if err := conf.Send(to, subject, body); err != nil {
+		log.Printf("[worker reminders] send %s to %s: %v", kind, to, err)
+		_ = w.Repo.DeleteLoanNotification(ctx, n.ID)
+		return 0
+	}
+	return 1
+}
+
+// adminRecipients ADMIN_EMAILS + 数据库中的管理员，统一小写去空白后去重
+func (w *Runner) adminRecipients(ctx context.Context) ([]string, error) {
+	fromDB, err := w.Repo.AdminEmails(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, e := range append(append([]string{}, w.Cfg.AdminEmails...), fromDB...) {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e != "" && !seen[e] {
+			seen[e] = true
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func reminderBody(row db.DueLoanRow, sentence string) string {
+	return fmt.Sprintf(`
+<div style="font-family:Arial,sans-serif; font-size:14px; color:#222">
+  <p>Hello,</p>
+  <p>The tool <b>%s</b> (serial %s) %s</p>
+  <p>Borrowed at: %s</p>
+  <hr/>
+  <p style="color:#666">This is an automated reminder.</p>
+</div>
+`, html.EscapeString(row.Name), html.EscapeString(row.Serial), sentence, row.BorrowedAt.Format(time.RFC1123))
+}
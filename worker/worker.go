@@ -0,0 +1,75 @@
+// worker/worker.go
+package worker
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"Gin_postgres_redis_rent_tool/app"
+	"Gin_postgres_redis_rent_tool/db"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Runner 进程内后台任务；多副本部署时靠 Redis 锁保证每一轮只有一个副本执行
+type Runner struct {
+	Repo *db.Repo
+	RDB  *redis.Client
+	Cfg  app.Config
+
+	instance string
+}
+
+func New(a *app.App) *Runner {
+	host, _ := os.Hostname()
+	return &Runner{
+		Repo:     db.NewRepo(a.DB),
+		RDB:      a.RDB,
+		Cfg:      a.Config,
+		instance: host + ":" + uuid.NewString(),
+	}
+}
+
+// Start 启动所有后台任务，ctx 取消时退出
+func (w *Runner) Start(ctx context.Context) {
+	go w.every(ctx, "waitlist", time.Minute, w.sweepWaitlist)
+	go w.every(ctx, "reminders", w.Cfg.ReminderInterval, w.sweepReminders)
+	go w.every(ctx, "condition", time.Minute, w.sweepConditionAlerts)
+}
+
+// every 每隔 interval 抢一次锁，抢到才执行 fn
+// 锁的 TTL 略小于 interval 且不主动释放：同一轮内其他副本抢不到，下一轮重新竞争
+func (w *Runner) every(ctx context.Context, name string, interval time.Duration, fn func(context.Context) error) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		lockTTL := interval - interval/10
+		ok, err := w.RDB.SetNX(ctx, "worker:lock:"+name, w.instance, lockTTL).Result()
+		if err != nil {
+			log.Printf("[worker %s] lock: %v", name, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := fn(ctx); err != nil {
+			log.Printf("[worker %s] %v", name, err)
+		}
+	}
+}
+
+func (w *Runner) sweepWaitlist(ctx context.Context) error {
+	n, err := w.Repo.SweepWaitlist(ctx)
+	if n > 0 {
+		log.Printf("[worker waitlist] advanced %d item(s)", n)
+	}
+	return err
+}